@@ -0,0 +1,116 @@
+// Package apperror classifies errors by what went wrong for the caller, so the
+// HTTP layer maps them to status codes in one place instead of per handler.
+package apperror
+
+import "errors"
+
+type Kind string
+
+const (
+	KindInternal      Kind = "internal"
+	KindNotFound      Kind = "not_found"
+	KindForbidden     Kind = "forbidden"
+	KindConflict      Kind = "conflict"
+	KindValidation    Kind = "validation"
+	KindUnprocessable Kind = "unprocessable"
+	KindUnauthorized  Kind = "unauthorized"
+	KindRateLimited   Kind = "rate_limited"
+
+	// KindPreconditionFailed is a write based on a stale read, see If-Match.
+	KindPreconditionFailed   Kind = "precondition_failed"
+	KindPreconditionRequired Kind = "precondition_required"
+)
+
+// Error is an error of a known kind. Message is meant for the client and
+// must not leak internals, the wrapped Err is only logged.
+type Error struct {
+	Kind    Kind
+	Message string
+	Details interface{}
+	Err     error
+}
+
+func (e *Error) Error() string {
+	var cause *Error
+	if e.Err != nil && !errors.As(e.Err, &cause) {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns e with details for the client. The result still
+// matches e with errors.Is, so sentinel errors can carry details too.
+func (e *Error) WithDetails(details interface{}) *Error {
+	return &Error{Kind: e.Kind, Message: e.Message, Details: details, Err: e}
+}
+
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap classifies err, keeping it as the cause.
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+func NotFound(message string) *Error {
+	return New(KindNotFound, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(KindForbidden, message)
+}
+
+func Conflict(message string) *Error {
+	return New(KindConflict, message)
+}
+
+func Validation(message string) *Error {
+	return New(KindValidation, message)
+}
+
+func Unprocessable(message string) *Error {
+	return New(KindUnprocessable, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(KindUnauthorized, message)
+}
+
+func PreconditionFailed(message string) *Error {
+	return New(KindPreconditionFailed, message)
+}
+
+func PreconditionRequired(message string) *Error {
+	return New(KindPreconditionRequired, message)
+}
+
+func RateLimited(message string) *Error {
+	return New(KindRateLimited, message)
+}
+
+// Classifier is implemented by error types that carry data of their own and
+// describe themselves as an Error, e.g. with the data as details.
+type Classifier interface {
+	AppError() *Error
+}
+
+// From returns the classification of err, nil when err is unclassified and
+// therefore internal.
+func From(err error) *Error {
+	var classifier Classifier
+	if errors.As(err, &classifier) {
+		return classifier.AppError()
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	return nil
+}
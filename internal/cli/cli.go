@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/config"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/database"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-redis/redis/v8"
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: be-appointment-system <command> [arguments]
+
+commands:
+  serve                          run the HTTP API (default)
+  worker                         run the outbox relay, webhook dispatcher and background jobs
+  migrate up                     apply all pending migrations
+  migrate down [n|all]           revert the last n migrations (default 1)
+  migrate status                 list migrations and the current version
+  seed                           insert demo users and appointments
+  user create                    create a user, see "user create -h"
+  user disable -username NAME    block a user and end their sessions
+  user reset-password            set a new password, see "user reset-password -h"
+`
+
+var errUsage = errors.New("invalid arguments")
+
+// Run executes the command given by args, without the program name.
+func Run(args []string) error {
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "help" || command == "-h" || command == "--help" {
+		fmt.Print(usage)
+		return nil
+	}
+
+	commands := map[string]func(cfg *config.Config) error{
+		"serve":   serve,
+		"worker":  runWorker,
+		"migrate": func(cfg *config.Config) error { return migrate(cfg, args) },
+		"seed":    seed,
+		"user":    func(cfg *config.Config) error { return user(cfg, args) },
+	}
+
+	run, ok := commands[command]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+
+	// .env is optional, variables already set in the environment win
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// the standard log package writes through it as well
+	slog.SetDefault(utils.NewLogger(os.Stdout, cfg.Log.Format, cfg.Log.Level))
+
+	err = run(cfg)
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, usage)
+	}
+
+	return err
+}
+
+func openDb(cfg *config.Config) (*sql.DB, error) {
+	db, err := database.InitDbConnection(cfg.Postgres.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to database: %w", err)
+	}
+
+	return db, nil
+}
+
+func openRedis(cfg *config.Config) (*redis.Client, error) {
+	redisClient := database.NewRedisClient(cfg.Redis.Options())
+	if _, err := redisClient.Ping(context.Background()).Result(); err != nil {
+		return nil, fmt.Errorf("redis connection failed: %w", err)
+	}
+	log.Println("check: redis connected")
+
+	return redisClient, nil
+}
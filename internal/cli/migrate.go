@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/config"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/database"
+)
+
+func migrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: migrate needs up, down or status", errUsage)
+	}
+
+	db, err := openDb(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := newMigrator(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %06d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if args[1] == "all" {
+				steps = int(^uint(0) >> 1)
+			} else if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("%w: migrate down takes a positive number or all", errUsage)
+			}
+		}
+
+		reverted, err := migrator.Down(steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %06d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, version, dirty, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d_%-30s %s\n", s.Version, s.Name, state)
+		}
+
+		fmt.Printf("current version: %d", version)
+		if dirty {
+			fmt.Print(" (dirty)")
+		}
+		fmt.Println()
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown migrate command %q", errUsage, args[0])
+	}
+}
+
+func newMigrator(db *sql.DB, cfg *config.Config) (*database.Migrator, error) {
+	return database.NewMigrator(db, cfg.Postgres.Schema)
+}
+
+// verifySchema refuses to run against a schema at another version than the
+// embedded migrations.
+func verifySchema(db *sql.DB, cfg *config.Config) error {
+	migrator, err := newMigrator(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	return migrator.Verify()
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/config"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+const seedPassword = "demo-password"
+
+type seedUser struct {
+	username, name, role, timezone string
+}
+
+type seedAppointment struct {
+	title       string
+	host        string
+	hourUTC     int // start hour on the next weekday, UTC
+	minutes     int
+	invitees    []string
+	optional    []string
+	description string
+}
+
+// demo users spread over timezones, so overlapping working hours are rare
+var seedUsers = []seedUser{
+	{"alice", "Alice Pratama", models.UserRoleAdmin, "Asia/Jakarta"},
+	{"daiki", "Daiki Tanaka", models.UserRoleStaff, "Asia/Tokyo"},
+	{"emma", "Emma Walsh", models.UserRoleStaff, "Australia/Sydney"},
+	{"chloe", "Chloe Martin", models.UserRoleStaff, "Europe/London"},
+	{"bob", "Bob Miller", models.UserRoleStaff, "America/New_York"},
+}
+
+var seedAppointments = []seedAppointment{
+	{"[demo] APAC sync", "alice", 2, 60, []string{"daiki", "emma"}, nil, "Weekly sync of the APAC team."},
+	{"[demo] Transatlantic review", "chloe", 14, 45, []string{"bob"}, []string{"alice"}, "Review of the release plan."},
+	{"[demo] Onboarding 1:1", "bob", 15, 30, []string{"chloe"}, nil, ""},
+}
+
+// seed inserts the demo data. Running it again leaves existing rows alone.
+func seed(cfg *config.Config) error {
+	db, err := openDb(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = verifySchema(db, cfg); err != nil {
+		return err
+	}
+
+	hash, err := utils.HashPassword(seedPassword)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	userIds := make(map[string]int)
+	for _, u := range seedUsers {
+		_, err = tx.Exec(`
+			INSERT INTO users (name, username, role, timezone, password_hash)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (username) DO NOTHING;
+		`, u.name, u.username, u.role, u.timezone, hash)
+		if err != nil {
+			return fmt.Errorf("seed user %s: %w", u.username, err)
+		}
+
+		var id int
+		err = tx.QueryRow(`SELECT user_id FROM users WHERE username = $1;`, u.username).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("seed user %s: %w", u.username, err)
+		}
+		userIds[u.username] = id
+	}
+
+	day := nextWeekday(time.Now().UTC())
+	created := 0
+	for _, a := range seedAppointments {
+		var exists bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM appointments WHERE host_id = $1 AND title = $2);
+		`, userIds[a.host], a.title).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		if err = insertSeedAppointment(tx, a, day, userIds); err != nil {
+			return fmt.Errorf("seed appointment %q: %w", a.title, err)
+		}
+		created++
+	}
+
+	fmt.Printf("seeded %d users and %d appointments, demo users sign in with password %q\n", len(seedUsers), created, seedPassword)
+	return nil
+}
+
+func insertSeedAppointment(tx *sql.Tx, a seedAppointment, day time.Time, userIds map[string]int) error {
+	start := day.Add(time.Duration(a.hourUTC) * time.Hour)
+	end := start.Add(time.Duration(a.minutes) * time.Minute)
+
+	var appointmentId int
+	err := tx.QueryRow(`
+		INSERT INTO appointments (host_id, title, description, start_time, end_time)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING appointment_id;
+	`, userIds[a.host], a.title, a.description, start, end).Scan(&appointmentId)
+	if err != nil {
+		return err
+	}
+
+	roles := map[string][]string{
+		models.InvitationRoleRequired: a.invitees,
+		models.InvitationRoleOptional: a.optional,
+	}
+	for role, usernames := range roles {
+		for _, username := range usernames {
+			_, err = tx.Exec(`
+				INSERT INTO invitations (appointment_id, invitee_id, role, status)
+				VALUES ($1, $2, $3, $4);
+			`, appointmentId, userIds[username], role, models.InvitationStatusPending)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// nextWeekday returns midnight UTC of the next day that is not a weekend.
+func nextWeekday(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
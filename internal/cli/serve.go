@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/config"
+	handlers "github.com/ghofaralhasyim/be-appointment-system/internal/http"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/middleware"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/routes"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/tracing"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/worker"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type CustomValidator struct {
+	validator *validator.Validate
+}
+
+func (cv *CustomValidator) Validate(i interface{}) error {
+	return cv.validator.Struct(i)
+}
+
+func serve(cfg *config.Config) error {
+	echo := echo.New()
+
+	v := validator.New()
+	v.RegisterValidation("ISOdate", utils.ISODateValidator)
+	v.RegisterValidation("slug", utils.SlugValidator)
+	echo.Validator = &CustomValidator{validator: v}
+
+	echo.HTTPErrorHandler = handlers.ErrorHandler
+	echo.IPExtractor = middleware.ClientIPExtractor()
+
+	echo.Use(middleware.RequestIdMiddleware)
+	echo.Use(middleware.TracingMiddleware)
+	echo.Use(middleware.MetricsMiddleware)
+	echo.Use(middleware.LoggingMiddleware)
+	echo.Use(middleware.CORSMiddleware)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("tracing: %v", err)
+		}
+	}()
+
+	db, err := openDb(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = verifySchema(db, cfg); err != nil {
+		return err
+	}
+
+	redisClient, err := openRedis(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err = metrics.RegisterDB(db); err != nil {
+		return err
+	}
+	metrics.InstrumentRedis(redisClient)
+	tracing.InstrumentRedis(redisClient)
+
+	echo.Server.ReadTimeout = time.Duration(cfg.Server.ReadTimeoutSeconds) * time.Second
+
+	healthService := routes.SetupRoutes(echo, db, redisClient, cfg)
+
+	if cfg.IsProduction() {
+		drainDelay := time.Duration(cfg.Server.DrainSeconds) * time.Second
+		utils.StartServerWithGracefulShutdown(echo, cfg.Server.Address(), healthService.Drain, drainDelay)
+	} else {
+		utils.StartServer(echo, cfg.Server.Address())
+	}
+
+	return nil
+}
+
+func runWorker(cfg *config.Config) error {
+	db, err := openDb(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = verifySchema(db, cfg); err != nil {
+		return err
+	}
+
+	redisClient, err := openRedis(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	worker.Run(ctx, db, redisClient, cfg.Worker)
+	log.Println("worker stopped")
+
+	return nil
+}
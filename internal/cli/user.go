@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/config"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+func user(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: user needs create, disable or reset-password", errUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("user "+command, flag.ContinueOnError)
+	username := flags.String("username", "", "username of the account")
+
+	var name, timezone, role, password *string
+	switch command {
+	case "create":
+		name = flags.String("name", "", "display name")
+		timezone = flags.String("timezone", "UTC", "IANA timezone, e.g. Asia/Jakarta")
+		role = flags.String("role", models.UserRoleStaff, "admin or staff")
+		password = flags.String("password", "", "password, generated when empty")
+	case "reset-password":
+		password = flags.String("password", "", "new password, generated when empty")
+	case "disable":
+	default:
+		return fmt.Errorf("%w: unknown user command %q", errUsage, command)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("%w: -username is required", errUsage)
+	}
+
+	generated := false
+	if password != nil && *password == "" {
+		p, err := utils.GeneratePassword()
+		if err != nil {
+			return err
+		}
+		*password = p
+		generated = true
+	}
+
+	db, err := openDb(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = verifySchema(db, cfg); err != nil {
+		return err
+	}
+
+	redisClient, err := openRedis(cfg)
+	if err != nil {
+		return err
+	}
+
+	redisRepo := repositories.NewRedisRepository(redisClient)
+	userService := services.NewUserService(repositories.NewUserRepository(db), redisRepo, cfg.JWT.TokenIssuer(), cfg.RateLimit.LockoutPolicy(),
+		repositories.NewListCache(redisRepo, cfg.Cache.ListTTL()))
+	ctx := context.Background()
+
+	switch command {
+	case "create":
+		if *name == "" {
+			*name = *username
+		}
+
+		created, err := userService.CreateUser(ctx, &models.User{
+			Name:     *name,
+			Username: *username,
+			Role:     *role,
+			Timezone: *timezone,
+		}, *password)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created user %s (id %d, %s)\n", created.Username, created.UserId, created.Role)
+
+	case "reset-password":
+		if err := userService.ResetPassword(ctx, *username, *password); err != nil {
+			return err
+		}
+		fmt.Printf("password of %s reset\n", *username)
+
+	case "disable":
+		if err := userService.DisableUser(ctx, *username); err != nil {
+			return err
+		}
+		fmt.Printf("user %s disabled, open sessions ended\n", *username)
+	}
+
+	if generated {
+		fmt.Printf("password: %s\n", *password)
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+const StageProduction = "production"
+
+// Config is the whole configuration of the application. Values come from the
+// defaults below, then the YAML file named by CONFIG_FILE (config.yaml when
+// present), then the environment, which .env adds to without overriding.
+type Config struct {
+	Stage string `yaml:"stage" env:"STAGE_STATUS" validate:"required"`
+
+	Server   ServerConfig   `yaml:"server"`
+	Postgres PostgresConfig `yaml:"postgres"`
+	Redis    RedisConfig    `yaml:"redis"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Meeting  MeetingConfig  `yaml:"meeting"`
+	Worker   WorkerConfig   `yaml:"worker"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Log      LogConfig      `yaml:"log"`
+
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Cache     CacheConfig     `yaml:"cache"`
+}
+
+type ServerConfig struct {
+	Host               string `yaml:"host" env:"SERVER_HOST"`
+	Port               int    `yaml:"port" env:"SERVER_PORT" validate:"min=1,max=65535"`
+	ReadTimeoutSeconds int    `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" validate:"min=1"`
+	DrainSeconds       int    `yaml:"drain_seconds" env:"SERVER_DRAIN_SECONDS" validate:"min=0"`
+}
+
+type JWTConfig struct {
+	SecretKey          string `yaml:"secret_key" env:"JWT_SECRET_KEY" validate:"required,min=16"`
+	RefreshKey         string `yaml:"refresh_key" env:"JWT_REFRESH_KEY" validate:"required,min=16,nefield=SecretKey"`
+	ExpireHours        int    `yaml:"expire_hours" env:"JWT_EXPIRE_HOURS" validate:"min=1"`
+	RefreshExpireHours int    `yaml:"refresh_expire_hours" env:"JWT_REFRESH_EXPIRE_HOURS" validate:"min=1"`
+}
+
+func (c JWTConfig) TokenIssuer() *utils.TokenIssuer {
+	return utils.NewTokenIssuer(c.SecretKey, c.RefreshKey,
+		time.Duration(c.ExpireHours)*time.Hour, time.Duration(c.RefreshExpireHours)*time.Hour)
+}
+
+type MeetingConfig struct {
+	Provider    string `yaml:"provider" env:"MEETING_PROVIDER" validate:"omitempty,oneof=static fake"`
+	UrlTemplate string `yaml:"url_template" env:"MEETING_URL_TEMPLATE" validate:"required_if=Provider static,omitempty,url"`
+}
+
+func (c ServerConfig) Address() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+type WorkerConfig struct {
+	Concurrency int `yaml:"concurrency" env:"WORKER_CONCURRENCY" validate:"min=1"`
+}
+
+// TracingConfig selects where spans go. The variables are the standard
+// OpenTelemetry ones; "none" keeps tracing off.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" validate:"oneof=none otlp stdout"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" validate:"omitempty,url"`
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME" validate:"required"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG" validate:"min=0,max=1"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" validate:"oneof=debug info warn error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" validate:"oneof=json text"`
+}
+
+// RateLimitConfig holds the request limits per route group, counted in
+// sliding windows, and the lockout of usernames after failed sign-ins. A
+// limit or lockout threshold of 0 turns it off.
+type RateLimitConfig struct {
+	LoginLimit               int `yaml:"login_limit" env:"RATE_LIMIT_LOGIN" validate:"min=0"`
+	LoginWindowSeconds       int `yaml:"login_window" env:"RATE_LIMIT_LOGIN_WINDOW" validate:"min=1"`
+	RefreshLimit             int `yaml:"refresh_limit" env:"RATE_LIMIT_REFRESH" validate:"min=0"`
+	RefreshWindowSeconds     int `yaml:"refresh_window" env:"RATE_LIMIT_REFRESH_WINDOW" validate:"min=1"`
+	AppointmentLimit         int `yaml:"appointment_limit" env:"RATE_LIMIT_APPOINTMENT" validate:"min=0"`
+	AppointmentWindowSeconds int `yaml:"appointment_window" env:"RATE_LIMIT_APPOINTMENT_WINDOW" validate:"min=1"`
+
+	LockoutThreshold     int `yaml:"lockout_threshold" env:"LOGIN_LOCKOUT_THRESHOLD" validate:"min=0"`
+	LockoutWindowSeconds int `yaml:"lockout_window" env:"LOGIN_LOCKOUT_WINDOW" validate:"min=1"`
+	LockoutSeconds       int `yaml:"lockout_seconds" env:"LOGIN_LOCKOUT_SECONDS" validate:"min=1"`
+	LockoutMaxSeconds    int `yaml:"lockout_max_seconds" env:"LOGIN_LOCKOUT_MAX_SECONDS" validate:"gtefield=LockoutSeconds"`
+}
+
+func (c RateLimitConfig) LoginPolicy() models.RateLimitPolicy {
+	return models.RateLimitPolicy{Limit: c.LoginLimit, Window: time.Duration(c.LoginWindowSeconds) * time.Second}
+}
+
+func (c RateLimitConfig) RefreshPolicy() models.RateLimitPolicy {
+	return models.RateLimitPolicy{Limit: c.RefreshLimit, Window: time.Duration(c.RefreshWindowSeconds) * time.Second}
+}
+
+func (c RateLimitConfig) AppointmentPolicy() models.RateLimitPolicy {
+	return models.RateLimitPolicy{Limit: c.AppointmentLimit, Window: time.Duration(c.AppointmentWindowSeconds) * time.Second}
+}
+
+func (c RateLimitConfig) LockoutPolicy() models.LockoutPolicy {
+	return models.LockoutPolicy{
+		Threshold:   c.LockoutThreshold,
+		Window:      time.Duration(c.LockoutWindowSeconds) * time.Second,
+		Duration:    time.Duration(c.LockoutSeconds) * time.Second,
+		MaxDuration: time.Duration(c.LockoutMaxSeconds) * time.Second,
+	}
+}
+
+// CacheConfig holds how long the appointment and invitation lists of a user
+// stay cached in Redis. A ttl of 0 turns the cache off.
+type CacheConfig struct {
+	ListTTLSeconds int `yaml:"list_ttl" env:"CACHE_LIST_TTL" validate:"min=0,max=3600"`
+}
+
+func (c CacheConfig) ListTTL() time.Duration {
+	return time.Duration(c.ListTTLSeconds) * time.Second
+}
+
+func defaults() Config {
+	return Config{
+		Stage: "dev",
+		Server: ServerConfig{
+			Host:               "127.0.0.1",
+			Port:               8080,
+			ReadTimeoutSeconds: 60,
+			DrainSeconds:       5,
+		},
+		Postgres: PostgresConfig{
+			Port:     5432,
+			SSLMode:  "disable",
+			TimeZone: "UTC",
+			Schema:   "stg_appointment",
+		},
+		Redis: RedisConfig{
+			Port: 6379,
+		},
+		JWT: JWTConfig{
+			ExpireHours:        1,
+			RefreshExpireHours: 24,
+		},
+		Worker: WorkerConfig{
+			Concurrency: 4,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "be-appointment-system",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		RateLimit: RateLimitConfig{
+			LoginLimit:               10,
+			LoginWindowSeconds:       60,
+			RefreshLimit:             30,
+			RefreshWindowSeconds:     60,
+			AppointmentLimit:         30,
+			AppointmentWindowSeconds: 60,
+			LockoutThreshold:         5,
+			LockoutWindowSeconds:     900,
+			LockoutSeconds:           60,
+			LockoutMaxSeconds:        3600,
+		},
+		Cache: CacheConfig{
+			ListTTLSeconds: 300,
+		},
+	}
+}
+
+// Load reads and validates the configuration. All problems are reported at
+// once, naming the environment variable of each field.
+func Load() (*Config, error) {
+	cfg := defaults()
+
+	path := os.Getenv("CONFIG_FILE")
+	required := path != ""
+	if path == "" {
+		path = "config.yaml"
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(content, &cfg); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Stage == StageProduction
+}
+
+// applyEnv overrides the fields tagged with env that are set in the
+// environment. An empty value clears a string, numbers ignore it.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := t.Field(i).Tag.Get("env")
+		value, ok := os.LookupEnv(name)
+		if name == "" || !ok {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int:
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", name, value)
+			}
+			field.SetInt(int64(n))
+		case reflect.Float64:
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", name, value)
+			}
+			field.SetFloat(f)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validate() error {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if name := field.Tag.Get("env"); name != "" {
+			return name
+		}
+		return field.Name
+	})
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		schema := sl.Current().Interface().(PostgresConfig).Schema
+		// public holds the version table of stg_appointment, see database.Migrator
+		if !schemaPattern.MatchString(schema) || schema == "public" {
+			sl.ReportError(schema, "POSTGRES_SCHEMA", "Schema", "identifier", "")
+		}
+	}, PostgresConfig{})
+
+	err := v.Struct(c)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	var problems []string
+	for _, e := range validationErrors {
+		problems = append(problems, fmt.Sprintf("%s: %s", e.Field(), describe(e)))
+	}
+
+	return fmt.Errorf("config: invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+}
+
+func describe(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required", "required_if":
+		return "is required"
+	case "min":
+		if e.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", e.Param())
+		}
+		return fmt.Sprintf("must be at least %s", e.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", e.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(e.Param(), " ", ", "))
+	case "nefield":
+		return "must differ from JWT_SECRET_KEY"
+	case "gtefield":
+		return "must be at least LOGIN_LOCKOUT_SECONDS"
+	case "url":
+		return "must be a url"
+	case "identifier":
+		return "must be a lower case identifier other than public"
+	default:
+		return fmt.Sprintf("failed %s validation", e.Tag())
+	}
+}
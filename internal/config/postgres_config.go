@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var schemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// PostgresConfig locates the database. Schema holds the application tables,
+// environments sharing a database use different schemas.
+type PostgresConfig struct {
+	Host     string `yaml:"host" env:"POSTGRES_HOST" validate:"required"`
+	Port     int    `yaml:"port" env:"POSTGRES_PORT" validate:"min=1,max=65535"`
+	User     string `yaml:"user" env:"POSTGRES_USER" validate:"required"`
+	Password string `yaml:"password" env:"POSTGRES_PASSWORD"`
+	Name     string `yaml:"name" env:"POSTGRES_NAME" validate:"required"`
+	SSLMode  string `yaml:"ssl_mode" env:"POSTGRES_SSL_MODE" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	TimeZone string `yaml:"time_zone" env:"POSTGRES_TIME_ZONE" validate:"required"`
+	Schema   string `yaml:"schema" env:"POSTGRES_SCHEMA"`
+}
+
+// DSN builds the lib/pq connection string. Queries use unqualified table
+// names, the schema is picked by search_path.
+func (c PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s search_path=%s,public",
+		quoteDSN(c.Host), c.Port, quoteDSN(c.User), quoteDSN(c.Password), quoteDSN(c.Name),
+		c.SSLMode, quoteDSN(c.TimeZone), c.Schema,
+	)
+}
+
+// quoteDSN quotes a value for the key=value connection string format.
+func quoteDSN(value string) string {
+	escaped := ""
+	for _, r := range value {
+		if r == '\\' || r == '\'' {
+			escaped += `\`
+		}
+		escaped += string(r)
+	}
+	return "'" + escaped + "'"
+}
@@ -1,26 +1,22 @@
 package config
 
 import (
-	"os"
+	"fmt"
 
-	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
 	"github.com/go-redis/redis/v8"
 )
 
-func NewRedisConfig() (redis.Options, error) {
-
-	var client redis.Options
-
-	connUrl, err := utils.ConnURLBuilder("redis")
-	if err != nil {
-		return client, err
-	}
+type RedisConfig struct {
+	Host     string `yaml:"host" env:"REDIS_HOST" validate:"required"`
+	Port     int    `yaml:"port" env:"REDIS_PORT" validate:"min=1,max=65535"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD"`
+	DB       int    `yaml:"db" env:"REDIS_DB_NUMBER" validate:"min=0"`
+}
 
-	client = redis.Options{
-		Addr:     connUrl,
-		Password: os.Getenv("REDIS_PASSWORD"),
-		DB:       0,
+func (c RedisConfig) Options() redis.Options {
+	return redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Password: c.Password,
+		DB:       c.DB,
 	}
-
-	return client, nil
 }
@@ -1,12 +1,17 @@
 package http
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
 	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
 	"github.com/go-playground/validator/v10"
@@ -24,10 +29,21 @@ func NewAppointmentHandler(appointmentService services.AppointmentService) *Appo
 }
 
 type appointmentRequest struct {
-	Title      string    `json:"title" validate:"required"`
-	StartTime  time.Time `json:"start_time" validate:"required,ISOdate"`
-	EndTime    time.Time `json:"end_time" validate:"required,ISOdate"`
-	InviteeIds []int     `json:"invitee_ids" validate:"required"`
+	Title              string    `json:"title" validate:"required"`
+	Description        string    `json:"description" validate:"max=10000"`
+	Location           string    `json:"location" validate:"max=255"`
+	VideoMeeting       bool      `json:"video_meeting"`
+	TravelMinutes      int       `json:"travel_minutes" validate:"min=0,max=720"`
+	StartTime          time.Time `json:"start_time" validate:"required,ISOdate"`
+	EndTime            time.Time `json:"end_time" validate:"required,ISOdate,gtfield=StartTime"`
+	InviteeIds         []int     `json:"invitee_ids" validate:"required"`
+	OptionalInviteeIds []int     `json:"optional_invitee_ids"`
+	FyiInviteeIds      []int     `json:"fyi_invitee_ids"`
+	Quorum             *int      `json:"quorum" validate:"omitempty,min=1"`
+	IsOpen             bool      `json:"is_open"`
+	Capacity           *int      `json:"capacity" validate:"omitempty,min=1"`
+	ResourceIds        []int     `json:"resource_ids"`
+	Tags               []string  `json:"tags" validate:"max=20,dive,max=50"`
 }
 
 func (h *AppointmentHandler) CreateAppointment(c echo.Context) error {
@@ -67,22 +83,36 @@ func (h *AppointmentHandler) CreateAppointment(c echo.Context) error {
 	}
 
 	dataAppointment := models.Appointment{
-		Title:      req.Title,
-		HostId:     userId,
-		StartTime:  req.StartTime,
-		EndTime:    req.EndTime,
-		InviteeIds: req.InviteeIds,
+		Title:              req.Title,
+		Description:        utils.SanitizeHTML(req.Description),
+		Location:           req.Location,
+		VideoMeeting:       req.VideoMeeting,
+		TravelMinutes:      req.TravelMinutes,
+		HostId:             userId,
+		StartTime:          req.StartTime,
+		EndTime:            req.EndTime,
+		Quorum:             req.Quorum,
+		IsOpen:             req.IsOpen,
+		Capacity:           req.Capacity,
+		ResourceIds:        utils.UniqueInts(req.ResourceIds),
+		InviteeIds:         req.InviteeIds,
+		OptionalInviteeIds: req.OptionalInviteeIds,
+		FyiInviteeIds:      req.FyiInviteeIds,
+		Tags:               utils.NormalizeTags(req.Tags),
 	}
 
-	createdAppointment, err := h.appointmentService.CreateAppointment(&dataAppointment)
+	createdAppointment, err := h.appointmentService.CreateAppointment(c.Request().Context(), &dataAppointment)
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"message": "failed create appointment - internal server error",
-			"details": nil,
-		})
+		if errors.Is(err, repositories.ErrResourceNotFound) {
+			return apperror.Wrap(apperror.KindValidation, "one or more resources do not exist", err)
+		}
+
+		return err
 	}
 
+	metrics.AppointmentsCreated.WithLabelValues("api").Inc()
+
+	c.Response().Header().Set(headerETag, etag(createdAppointment.Version))
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"message": "appointment created",
 		"data":    createdAppointment,
@@ -98,18 +128,499 @@ func (h *AppointmentHandler) GetAppointments(c echo.Context) error {
 		})
 	}
 
-	appointments, err := h.appointmentService.GetAppointmentsByUserId(userId)
+	appointments, err := h.appointmentService.GetAppointmentsByUserId(c.Request().Context(), userId)
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"message": "failed create appointment - internal server error",
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    appointments,
+	})
+
+}
+
+// GetAppointment returns one appointment with its ETag, to be sent back as
+// If-Match when changing it.
+func (h *AppointmentHandler) GetAppointment(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	appointment, err := h.appointmentService.GetAppointment(c.Request().Context(), userId, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(headerETag, etag(appointment.Version))
+	if notModified(c, appointment.Version) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    appointment,
+	})
+}
+
+// GetAttendees lists the attendees of an appointment with their responses,
+// filtered by status and paged by limit and offset.
+func (h *AppointmentHandler) GetAttendees(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
 			"details": nil,
 		})
 	}
 
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	filter := models.AttendeeFilter{Status: c.QueryParam("status")}
+	switch filter.Status {
+	case "", models.InvitationStatusAccepted, models.InvitationStatusPending,
+		models.InvitationStatusRejected, models.InvitationStatusWaitlisted:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid status", "detail": nil})
+	}
+
+	pageParams := map[string]*int{
+		"limit":  &filter.Limit,
+		"offset": &filter.Offset,
+	}
+	for name, target := range pageParams {
+		if param := c.QueryParam(name); param != "" {
+			value, err := strconv.Atoi(param)
+			if err != nil || value < 0 {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid " + name, "detail": nil})
+			}
+			*target = value
+		}
+	}
+
+	page, err := h.appointmentService.GetAttendees(c.Request().Context(), userId, appointmentId, filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    page,
+	})
+}
+
+// SearchAppointments searches the appointments of the user by q (full-text on
+// title and description), host_id, participant_id, status, a from/to range
+// (RFC 3339), comma separated tags and resource_id, paged by limit and offset.
+func (h *AppointmentHandler) SearchAppointments(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	filter := models.AppointmentSearch{
+		Query:  strings.TrimSpace(c.QueryParam("q")),
+		Status: c.QueryParam("status"),
+	}
+
+	switch filter.Status {
+	case "", models.AppointmentStatusTentative, models.AppointmentStatusConfirmed,
+		models.AppointmentStatusAtRisk, models.AppointmentStatusCancelled:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid status", "detail": nil})
+	}
+
+	intParams := map[string]**int{
+		"host_id":        &filter.HostId,
+		"participant_id": &filter.ParticipantId,
+		"resource_id":    &filter.ResourceId,
+	}
+	for name, target := range intParams {
+		if param := c.QueryParam(name); param != "" {
+			value, err := strconv.Atoi(param)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid " + name, "detail": nil})
+			}
+			*target = &value
+		}
+	}
+
+	pageParams := map[string]*int{
+		"limit":  &filter.Limit,
+		"offset": &filter.Offset,
+	}
+	for name, target := range pageParams {
+		if param := c.QueryParam(name); param != "" {
+			value, err := strconv.Atoi(param)
+			if err != nil || value < 0 {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid " + name, "detail": nil})
+			}
+			*target = value
+		}
+	}
+
+	timeParams := map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	}
+	for name, target := range timeParams {
+		if param := c.QueryParam(name); param != "" {
+			value, err := time.Parse(time.RFC3339, param)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": name + " must in ISO 8601 format", "detail": nil})
+			}
+			*target = value
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "to must be after from", "detail": nil})
+	}
+
+	if tags := c.QueryParam("tags"); tags != "" {
+		filter.Tags = utils.NormalizeTags(strings.Split(tags, ","))
+	}
+
+	appointments, err := h.appointmentService.SearchAppointments(c.Request().Context(), userId, filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    appointments,
+	})
+}
+
+func (h *AppointmentHandler) GetOpenAppointments(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointments, err := h.appointmentService.GetOpenAppointments(c.Request().Context(), userId)
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "ok",
 		"data":    appointments,
 	})
+}
+
+func (h *AppointmentHandler) JoinAppointment(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	invitation, err := h.appointmentService.JoinAppointment(c.Request().Context(), userId, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	message := "joined appointment"
+	if invitation.Status == models.InvitationStatusWaitlisted {
+		message = "appointment is full, added to waitlist"
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": message,
+		"data":    invitation,
+	})
+}
+
+func (h *AppointmentHandler) GetFreeBusy(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid user id", "detail": nil})
+	}
+
+	startDate, endDate, err := utils.ParseTimeRange(c.QueryParam("start"), c.QueryParam("end"), 7*24*time.Hour)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": err.Error(), "detail": nil})
+	}
+
+	busy, err := h.appointmentService.GetFreeBusy(c.Request().Context(), userId, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data": map[string]interface{}{
+			"user_id": userId,
+			"start":   startDate,
+			"end":     endDate,
+			"busy":    busy,
+		},
+	})
+}
+
+func (h *AppointmentHandler) ExportICS(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	ics, err := h.appointmentService.ExportICS(c.Request().Context(), userId, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=appointment-%d.ics", appointmentId))
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", []byte(ics))
+}
+
+type updateAppointmentRequest struct {
+	Title         *string    `json:"title" validate:"omitempty,min=1"`
+	Description   *string    `json:"description" validate:"omitempty,max=10000"`
+	Location      *string    `json:"location" validate:"omitempty,max=255"`
+	TravelMinutes *int       `json:"travel_minutes" validate:"omitempty,min=0,max=720"`
+	StartTime     *time.Time `json:"start_time" validate:"omitempty,ISOdate"`
+	EndTime       *time.Time `json:"end_time" validate:"omitempty,ISOdate"`
+	Tags          *[]string  `json:"tags" validate:"omitempty,max=20,dive,max=50"`
+}
+
+type addInviteesRequest struct {
+	InviteeIds []int  `json:"invitee_ids" validate:"required,min=1"`
+	Role       string `json:"role" validate:"omitempty,oneof=required optional fyi"`
+}
+
+func (h *AppointmentHandler) UpdateAppointment(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	var req updateAppointmentRequest
+
+	if err := c.Bind(&req); err != nil {
+		errMsg := "Invalid request"
+		if strings.Contains(err.Error(), "parsing time") {
+			errMsg = "date must in ISO 8601 format"
+		}
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": errMsg, "detail": nil})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			field, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				field: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	patch := models.AppointmentPatch{
+		Title:         req.Title,
+		Location:      req.Location,
+		TravelMinutes: req.TravelMinutes,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+	}
+	if req.Description != nil {
+		description := utils.SanitizeHTML(*req.Description)
+		patch.Description = &description
+	}
+	if req.Tags != nil {
+		tags := utils.NormalizeTags(*req.Tags)
+		patch.Tags = &tags
+	}
+
+	appointment, err := h.appointmentService.UpdateAppointment(c.Request().Context(), userId, appointmentId, version, patch)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(headerETag, etag(appointment.Version))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "appointment updated",
+		"data":    appointment,
+	})
+}
+
+func (h *AppointmentHandler) AddInvitees(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	version, err := optionalIfMatchVersion(c)
+	if err != nil {
+		return err
+	}
 
+	var req addInviteesRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid request", "detail": nil})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			field, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				field: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.InvitationRoleRequired
+	}
+
+	newVersion, invitations, warnings, err := h.appointmentService.AddInvitees(c.Request().Context(), userId, appointmentId, version, role, utils.UniqueInts(req.InviteeIds))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(headerETag, etag(newVersion))
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message":  "invitees added",
+		"data":     invitations,
+		"warnings": warnings,
+	})
+}
+
+func (h *AppointmentHandler) RemoveInvitee(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	inviteeId, err := strconv.Atoi(c.Param("inviteeId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid invitee id", "detail": nil})
+	}
+
+	version, err := optionalIfMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	newVersion, err := h.appointmentService.RemoveInvitee(c.Request().Context(), userId, appointmentId, version, inviteeId)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(headerETag, etag(newVersion))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "invitee removed",
+		"data":    nil,
+	})
+}
+
+func (h *AppointmentHandler) CancelAppointment(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.appointmentService.CancelAppointment(c.Request().Context(), userId, appointmentId, version)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "appointment cancelled",
+		"data":    nil,
+	})
 }
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type AuditHandler struct {
+	auditService services.AuditService
+}
+
+func NewAuditHandler(auditService services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+func (h *AuditHandler) GetAppointmentHistory(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	appointmentId, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid appointment id", "detail": nil})
+	}
+
+	events, err := h.auditService.GetAppointmentHistory(c.Request().Context(), userId, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    events,
+	})
+}
+
+// SearchAuditEvents filters the audit trail by appointment_id, actor_id,
+// action and a from/to creation range (RFC 3339), paged by limit and offset.
+func (h *AuditHandler) SearchAuditEvents(c echo.Context) error {
+	var filter models.AuditFilter
+
+	intParams := map[string]**int{
+		"appointment_id": &filter.AppointmentId,
+		"actor_id":       &filter.ActorId,
+	}
+	for name, target := range intParams {
+		if param := c.QueryParam(name); param != "" {
+			value, err := strconv.Atoi(param)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid " + name, "detail": nil})
+			}
+			*target = &value
+		}
+	}
+
+	pageParams := map[string]*int{
+		"limit":  &filter.Limit,
+		"offset": &filter.Offset,
+	}
+	for name, target := range pageParams {
+		if param := c.QueryParam(name); param != "" {
+			value, err := strconv.Atoi(param)
+			if err != nil || value < 0 {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid " + name, "detail": nil})
+			}
+			*target = value
+		}
+	}
+
+	timeParams := map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	}
+	for name, target := range timeParams {
+		if param := c.QueryParam(name); param != "" {
+			value, err := time.Parse(time.RFC3339, param)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": name + " must in ISO 8601 format", "detail": nil})
+			}
+			*target = value
+		}
+	}
+
+	filter.Action = c.QueryParam("action")
+
+	events, err := h.auditService.SearchAuditEvents(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    events,
+	})
+}
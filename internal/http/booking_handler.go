@@ -0,0 +1,244 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type BookingHandler struct {
+	bookingService services.BookingService
+}
+
+func NewBookingHandler(bookingService services.BookingService) *BookingHandler {
+	return &BookingHandler{
+		bookingService: bookingService,
+	}
+}
+
+type availabilityWindowRequest struct {
+	Weekday int    `json:"weekday" validate:"min=0,max=6"`
+	Start   string `json:"start" validate:"required,datetime=15:04"`
+	End     string `json:"end" validate:"required,datetime=15:04"`
+}
+
+type eventTypeRequest struct {
+	Title               string                      `json:"title" validate:"required"`
+	DurationMinutes     int                         `json:"duration_minutes" validate:"required,min=5,max=1440"`
+	BufferBeforeMinutes int                         `json:"buffer_before_minutes" validate:"min=0"`
+	BufferAfterMinutes  int                         `json:"buffer_after_minutes" validate:"min=0"`
+	MinNoticeMinutes    int                         `json:"min_notice_minutes" validate:"min=0"`
+	MaxPerDay           *int                        `json:"max_per_day" validate:"omitempty,min=1"`
+	Availability        []availabilityWindowRequest `json:"availability" validate:"required,min=1,dive"`
+}
+
+type bookingPageRequest struct {
+	Slug        string             `json:"slug" validate:"required,slug,max=100"`
+	Title       string             `json:"title" validate:"required"`
+	Description string             `json:"description"`
+	EventTypes  []eventTypeRequest `json:"event_types" validate:"required,min=1,dive"`
+}
+
+type bookRequest struct {
+	EventTypeId int       `json:"event_type_id" validate:"required"`
+	StartTime   time.Time `json:"start_time" validate:"required,ISOdate"`
+	Name        string    `json:"name" validate:"required,max=255"`
+	Email       string    `json:"email" validate:"required,email"`
+	Notes       string    `json:"notes" validate:"max=255"`
+}
+
+func (h *BookingHandler) CreateBookingPage(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	var req bookingPageRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			fieldName, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				fieldName: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	page := models.BookingPage{
+		HostId:      userId,
+		Slug:        req.Slug,
+		Title:       req.Title,
+		Description: req.Description,
+	}
+
+	for _, et := range req.EventTypes {
+		eventType := models.EventType{
+			Title:               et.Title,
+			DurationMinutes:     et.DurationMinutes,
+			BufferBeforeMinutes: et.BufferBeforeMinutes,
+			BufferAfterMinutes:  et.BufferAfterMinutes,
+			MinNoticeMinutes:    et.MinNoticeMinutes,
+			MaxPerDay:           et.MaxPerDay,
+		}
+
+		for _, w := range et.Availability {
+			// "HH:MM" strings compare chronologically
+			if w.End <= w.Start {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"message": "bad request - validation failed",
+					"details": []map[string]string{{"availability": "window end must be after start"}},
+				})
+			}
+
+			eventType.Availability = append(eventType.Availability, models.AvailabilityWindow{
+				Weekday: w.Weekday,
+				Start:   w.Start,
+				End:     w.End,
+			})
+		}
+
+		page.EventTypes = append(page.EventTypes, eventType)
+	}
+
+	createdPage, err := h.bookingService.CreateBookingPage(c.Request().Context(), &page)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "booking page created",
+		"data":    createdPage,
+	})
+}
+
+func (h *BookingHandler) GetBookingPages(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	pages, err := h.bookingService.GetBookingPages(c.Request().Context(), userId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    pages,
+	})
+}
+
+func (h *BookingHandler) GetSlots(c echo.Context) error {
+	var eventTypeId int
+	if param := c.QueryParam("event_type_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid event type id", "detail": nil})
+		}
+		eventTypeId = id
+	}
+
+	startDate, endDate, err := utils.ParseTimeRange(c.QueryParam("start"), c.QueryParam("end"), 7*24*time.Hour)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": err.Error(), "detail": nil})
+	}
+
+	slots, err := h.bookingService.GetSlots(c.Request().Context(), c.Param("slug"), eventTypeId, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	// let the booker see slots in their own timezone
+	if tz := c.QueryParam("timezone"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "timezone must be a valid IANA timezone", "detail": nil})
+		}
+
+		for i := range slots {
+			slots[i].Timezone = tz
+			for j := range slots[i].Slots {
+				slots[i].Slots[j].StartTime = slots[i].Slots[j].StartTime.In(loc)
+				slots[i].Slots[j].EndTime = slots[i].Slots[j].EndTime.In(loc)
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    slots,
+	})
+}
+
+func (h *BookingHandler) Book(c echo.Context) error {
+	var req bookRequest
+
+	if err := c.Bind(&req); err != nil {
+		errMsg := "Invalid request"
+		if strings.Contains(err.Error(), "parsing time") {
+			errMsg = "date must in ISO 8601 format"
+		}
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": errMsg, "detail": nil})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			field, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				field: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	guest := models.Guest{
+		Name:  req.Name,
+		Email: req.Email,
+		Notes: req.Notes,
+	}
+
+	appointment, err := h.bookingService.Book(c.Request().Context(), c.Param("slug"), req.EventTypeId, req.StartTime, &guest)
+	if err != nil {
+		return err
+	}
+
+	metrics.AppointmentsCreated.WithLabelValues("booking").Inc()
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "appointment booked",
+		"data":    appointment,
+	})
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var statusByKind = map[apperror.Kind]int{
+	apperror.KindNotFound:      http.StatusNotFound,
+	apperror.KindForbidden:     http.StatusForbidden,
+	apperror.KindConflict:      http.StatusConflict,
+	apperror.KindValidation:    http.StatusBadRequest,
+	apperror.KindUnprocessable: http.StatusUnprocessableEntity,
+	apperror.KindUnauthorized:  http.StatusUnauthorized,
+	apperror.KindRateLimited:   http.StatusTooManyRequests,
+
+	apperror.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	apperror.KindPreconditionRequired: http.StatusPreconditionRequired,
+}
+
+// ErrorHandler renders the errors handlers return. Classified errors are
+// answered with the status of their kind and their message, echo errors keep
+// their status, anything else is logged and answered with a bare 500.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := "internal server error"
+	var details interface{}
+
+	var httpErr *echo.HTTPError
+	if appErr := apperror.From(err); appErr != nil && appErr.Kind != apperror.KindInternal {
+		status = statusByKind[appErr.Kind]
+		message = appErr.Message
+		details = appErr.Details
+	} else if errors.As(err, &httpErr) {
+		status = httpErr.Code
+		message = fmt.Sprint(httpErr.Message)
+	}
+
+	if status >= http.StatusInternalServerError {
+		ctx := c.Request().Context()
+		slog.ErrorContext(ctx, "request failed", "route", c.Path(), "error", err.Error())
+
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, map[string]interface{}{
+			"message": message,
+			"details": details,
+		})
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "writing error response", "error", err.Error())
+	}
+}
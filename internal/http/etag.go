@@ -0,0 +1,71 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+var (
+	errIfMatchRequired = apperror.PreconditionRequired("If-Match header with the ETag of the resource is required")
+	errIfMatchInvalid  = apperror.Validation("If-Match must be an ETag returned by the API or *")
+)
+
+// etag is the entity tag of a resource at the given version.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion returns the version the client based its change on, read
+// from If-Match. "*" matches any version and is returned as 0.
+func ifMatchVersion(c echo.Context) (int, error) {
+	value := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if value == "" {
+		return 0, errIfMatchRequired
+	}
+	if value == "*" {
+		return 0, nil
+	}
+
+	if len(value) < 3 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errIfMatchInvalid
+	}
+
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || version < 1 {
+		return 0, errIfMatchInvalid
+	}
+
+	return version, nil
+}
+
+// optionalIfMatchVersion is ifMatchVersion for changes that may be made
+// without one, a missing If-Match is taken as *.
+func optionalIfMatchVersion(c echo.Context) (int, error) {
+	if strings.TrimSpace(c.Request().Header.Get(headerIfMatch)) == "" {
+		return 0, nil
+	}
+	return ifMatchVersion(c)
+}
+
+// notModified tells whether the client already has the version, per
+// If-None-Match.
+func notModified(c echo.Context, version int) bool {
+	tag := etag(version)
+	for _, candidate := range strings.Split(c.Request().Header.Get(headerIfNoneMatch), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// contextWithHeader returns an echo context of a request carrying the header
+// when value is not empty.
+func contextWithHeader(name, value string) echo.Context {
+	req := httptest.NewRequest(http.MethodPatch, "/appointments/1", nil)
+	if value != "" {
+		req.Header.Set(name, value)
+	}
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr error
+	}{
+		{"missing", "", 0, errIfMatchRequired},
+		{"any version", "*", 0, nil},
+		{"version", `"3"`, 3, nil},
+		{"surrounding spaces", ` "12" `, 12, nil},
+		{"unquoted", "3", 0, errIfMatchInvalid},
+		{"weak tag", `W/"3"`, 0, errIfMatchInvalid},
+		{"empty tag", `""`, 0, errIfMatchInvalid},
+		{"not a number", `"abc"`, 0, errIfMatchInvalid},
+		{"zero", `"0"`, 0, errIfMatchInvalid},
+		{"negative", `"-1"`, 0, errIfMatchInvalid},
+		{"list", `"3", "4"`, 0, errIfMatchInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ifMatchVersion(contextWithHeader(headerIfMatch, tt.value))
+			if err != tt.wantErr {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("version %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionalIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr error
+	}{
+		{"missing is any version", "", 0, nil},
+		{"version", `"5"`, 5, nil},
+		{"invalid", "5", 0, errIfMatchInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := optionalIfMatchVersion(contextWithHeader(headerIfMatch, tt.value))
+			if err != tt.wantErr || got != tt.want {
+				t.Errorf("got %d, %v, want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"missing", "", false},
+		{"same version", `"4"`, true},
+		{"other version", `"3"`, false},
+		{"weak tag", `W/"4"`, true},
+		{"list", `"2", "4"`, true},
+		{"list without the version", `"2","3"`, false},
+		{"any", "*", true},
+		{"unquoted", "4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notModified(contextWithHeader(headerIfNoneMatch, tt.value), 4); got != tt.want {
+				t.Errorf("notModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type HealthHandler struct {
+	healthService services.HealthService
+}
+
+func NewHealthHandler(healthService services.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+// Liveness only tells the process is serving requests. It does not look at
+// dependencies, an outage of those must not get the pod restarted.
+func (h *HealthHandler) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    nil,
+	})
+}
+
+func (h *HealthHandler) Readiness(c echo.Context) error {
+	report := h.healthService.Readiness(c.Request().Context())
+	if !report.Ready {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"message": "not ready",
+			"data":    report,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    report,
+	})
+}
@@ -1,10 +1,11 @@
 package http
 
 import (
-	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
 	"github.com/labstack/echo/v4"
 )
@@ -28,13 +29,9 @@ func (h *InvitationHandler) GetInvitations(c echo.Context) error {
 		})
 	}
 
-	invitations, err := h.invitationService.GetInvitations(userId)
+	invitations, err := h.invitationService.GetInvitations(c.Request().Context(), userId)
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"message": "failed retrieve invitations - internal server error",
-			"details": nil,
-		})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -58,18 +55,28 @@ func (h *InvitationHandler) AcceptInvitation(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid address id", "detail": err})
 	}
 
-	err = h.invitationService.UpdateStatusInvitation(userId, invIdInt, "accepted")
+	version, err := ifMatchVersion(c)
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"message": "failed accept invitation - internal server error",
-			"details": nil,
-		})
+		return err
+	}
+
+	invitation, err := h.invitationService.UpdateStatusInvitation(c.Request().Context(), userId, invIdInt, "accepted", version)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(headerETag, etag(invitation.Version))
+
+	metrics.InvitationResponses.WithLabelValues(invitation.Status).Inc()
+
+	message := "invitation accepted"
+	if invitation.Status == models.InvitationStatusWaitlisted {
+		message = "appointment is full, invitation waitlisted"
 	}
 
 	return c.JSON(http.StatusAccepted, map[string]interface{}{
-		"message": "invitation accepted",
-		"data":    nil,
+		"message": message,
+		"data":    invitation,
 	})
 }
 
@@ -88,15 +95,20 @@ func (h *InvitationHandler) RejectInvitation(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid address id", "detail": err})
 	}
 
-	err = h.invitationService.UpdateStatusInvitation(userId, invIdInt, "rejected")
+	version, err := ifMatchVersion(c)
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"message": "failed reject invitation - internal server error",
-			"details": nil,
-		})
+		return err
 	}
 
+	invitation, err := h.invitationService.UpdateStatusInvitation(c.Request().Context(), userId, invIdInt, "rejected", version)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(headerETag, etag(invitation.Version))
+
+	metrics.InvitationResponses.WithLabelValues(models.InvitationStatusRejected).Inc()
+
 	return c.JSON(http.StatusAccepted, map[string]interface{}{
 		"message": "invitation rejected",
 		"data":    nil,
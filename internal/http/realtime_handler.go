@@ -0,0 +1,75 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+const streamHeartbeat = 25 * time.Second
+
+type RealtimeHandler struct {
+	realtimeService services.RealtimeService
+}
+
+func NewRealtimeHandler(realtimeService services.RealtimeService) *RealtimeHandler {
+	return &RealtimeHandler{
+		realtimeService: realtimeService,
+	}
+}
+
+// Stream pushes the user's events as Server-Sent Events until the client
+// disconnects. Comments are sent as heartbeat to keep proxies from closing it.
+func (h *RealtimeHandler) Stream(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	events, unsubscribe := h.realtimeService.Subscribe(userId)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	var id int
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				slog.ErrorContext(c.Request().Context(), "encode realtime event", "error", err)
+				continue
+			}
+
+			id++
+			if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", id, event.Type, data); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
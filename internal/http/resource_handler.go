@@ -0,0 +1,142 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type ResourceHandler struct {
+	resourceService services.ResourceService
+}
+
+func NewResourceHandler(resourceService services.ResourceService) *ResourceHandler {
+	return &ResourceHandler{
+		resourceService: resourceService,
+	}
+}
+
+type resourceRequest struct {
+	Name     string `json:"name" validate:"required"`
+	Type     string `json:"type" validate:"required,oneof=room equipment vehicle"`
+	Capacity *int   `json:"capacity" validate:"omitempty,min=1"`
+	Location string `json:"location"`
+	Timezone string `json:"timezone" validate:"required"`
+}
+
+func (h *ResourceHandler) GetResources(c echo.Context) error {
+	resources, err := h.resourceService.GetResources(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    resources,
+	})
+}
+
+func (h *ResourceHandler) CreateResource(c echo.Context) error {
+	var req resourceRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			fieldName, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				fieldName: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": []map[string]string{{"timezone": "timezone must be a valid IANA timezone"}},
+		})
+	}
+
+	resource, err := h.resourceService.CreateResource(c.Request().Context(), &models.Resource{
+		Name:     req.Name,
+		Type:     req.Type,
+		Capacity: req.Capacity,
+		Location: req.Location,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "resource created",
+		"data":    resource,
+	})
+}
+
+func (h *ResourceHandler) GetResourceBusy(c echo.Context) error {
+	resourceId, err := strconv.Atoi(c.Param("resourceId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid resource id", "detail": nil})
+	}
+
+	startDate, endDate, err := utils.ParseTimeRange(c.QueryParam("start"), c.QueryParam("end"), 7*24*time.Hour)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": err.Error(), "detail": nil})
+	}
+
+	busy, err := h.resourceService.GetResourceBusy(c.Request().Context(), resourceId, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data": map[string]interface{}{
+			"start": startDate,
+			"end":   endDate,
+			"busy":  busy,
+		},
+	})
+}
+
+func (h *ResourceHandler) GetResourceCalendar(c echo.Context) error {
+	resourceId, err := strconv.Atoi(c.Param("resourceId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid resource id", "detail": nil})
+	}
+
+	startDate, endDate, err := utils.ParseTimeRange(c.QueryParam("start"), c.QueryParam("end"), 7*24*time.Hour)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": err.Error(), "detail": nil})
+	}
+
+	resource, bookings, err := h.resourceService.GetResourceCalendar(c.Request().Context(), resourceId, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data": map[string]interface{}{
+			"resource": resource,
+			"bookings": bookings,
+		},
+	})
+}
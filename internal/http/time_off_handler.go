@@ -0,0 +1,325 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type TimeOffHandler struct {
+	timeOffService services.TimeOffService
+	holidayService services.HolidayService
+}
+
+func NewTimeOffHandler(timeOffService services.TimeOffService, holidayService services.HolidayService) *TimeOffHandler {
+	return &TimeOffHandler{
+		timeOffService: timeOffService,
+		holidayService: holidayService,
+	}
+}
+
+type timeOffRequest struct {
+	StartTime time.Time `json:"start_time" validate:"required,ISOdate"`
+	EndTime   time.Time `json:"end_time" validate:"required,ISOdate,gtfield=StartTime"`
+	Reason    string    `json:"reason" validate:"max=255"`
+	AutoReply string    `json:"auto_reply" validate:"max=255"`
+}
+
+type holidayCalendarRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type holidayCalendarUsersRequest struct {
+	UserIds []int `json:"user_ids" validate:"required,min=1"`
+}
+
+func (h *TimeOffHandler) CreateTimeOff(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	var req timeOffRequest
+
+	if err := c.Bind(&req); err != nil {
+		errMsg := "Invalid request"
+		if strings.Contains(err.Error(), "parsing time") {
+			errMsg = "date must in ISO 8601 format"
+		}
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": errMsg, "detail": nil})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			field, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				field: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	timeOff, err := h.timeOffService.CreateTimeOff(c.Request().Context(), &models.TimeOff{
+		UserId:    userId,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Reason:    req.Reason,
+		AutoReply: req.AutoReply,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "time off created",
+		"data":    timeOff,
+	})
+}
+
+func (h *TimeOffHandler) GetTimeOff(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	entries, err := h.timeOffService.GetTimeOff(c.Request().Context(), userId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    entries,
+	})
+}
+
+func (h *TimeOffHandler) DeleteTimeOff(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	timeOffId, err := strconv.Atoi(c.Param("timeOffId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid time off id", "detail": nil})
+	}
+
+	err = h.timeOffService.DeleteTimeOff(c.Request().Context(), userId, timeOffId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "time off deleted",
+		"data":    nil,
+	})
+}
+
+func (h *TimeOffHandler) GetHolidays(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	startDate, endDate, err := utils.ParseTimeRange(c.QueryParam("start"), c.QueryParam("end"), 365*24*time.Hour)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": err.Error(), "detail": nil})
+	}
+
+	holidays, err := h.holidayService.GetHolidays(c.Request().Context(), userId, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    holidays,
+	})
+}
+
+func (h *TimeOffHandler) GetHolidayCalendars(c echo.Context) error {
+	calendars, err := h.holidayService.GetHolidayCalendars(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    calendars,
+	})
+}
+
+func (h *TimeOffHandler) CreateHolidayCalendar(c echo.Context) error {
+	var req holidayCalendarRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			fieldName, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				fieldName: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	calendar, err := h.holidayService.CreateHolidayCalendar(c.Request().Context(), &models.HolidayCalendar{Name: req.Name})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "holiday calendar created",
+		"data":    calendar,
+	})
+}
+
+// ImportHolidays expects the .ics file in the multipart field "file".
+func (h *TimeOffHandler) ImportHolidays(c echo.Context) error {
+	calendarId, err := strconv.Atoi(c.Param("calendarId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid holiday calendar id", "detail": nil})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "file is required", "detail": nil})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "open uploaded file", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "failed read file", "detail": nil})
+	}
+	defer file.Close()
+
+	imported, err := h.holidayService.ImportHolidays(c.Request().Context(), calendarId, file)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "holidays imported",
+		"data": map[string]interface{}{
+			"imported": imported,
+		},
+	})
+}
+
+func (h *TimeOffHandler) GetHolidayCalendarUsers(c echo.Context) error {
+	calendarId, err := strconv.Atoi(c.Param("calendarId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid holiday calendar id", "detail": nil})
+	}
+
+	users, err := h.holidayService.GetCalendarUsers(c.Request().Context(), calendarId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    users,
+	})
+}
+
+// AssignHolidayCalendarUsers makes the holidays of the calendar apply to the
+// users, on top of the calendars they are already assigned to.
+func (h *TimeOffHandler) AssignHolidayCalendarUsers(c echo.Context) error {
+	calendarId, err := strconv.Atoi(c.Param("calendarId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid holiday calendar id", "detail": nil})
+	}
+
+	var req holidayCalendarUsersRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			fieldName, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				fieldName: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	err = h.holidayService.AssignUsers(c.Request().Context(), calendarId, req.UserIds)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "users assigned",
+		"data":    req,
+	})
+}
+
+func (h *TimeOffHandler) UnassignHolidayCalendarUser(c echo.Context) error {
+	calendarId, err := strconv.Atoi(c.Param("calendarId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid holiday calendar id", "detail": nil})
+	}
+
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid user id", "detail": nil})
+	}
+
+	err = h.holidayService.UnassignUser(c.Request().Context(), calendarId, userId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "user unassigned",
+		"data":    nil,
+	})
+}
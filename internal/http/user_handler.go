@@ -1,9 +1,13 @@
 package http
 
 import (
-	"log"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
 	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
 	"github.com/go-playground/validator/v10"
@@ -25,13 +29,9 @@ type refreshTokenRequest struct {
 }
 
 func (h *UserHandler) GetUsers(c echo.Context) error {
-	users, err := h.userService.GetUsers()
+	users, err := h.userService.GetUsers(c.Request().Context())
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"message": "failed retrieve users - internal server error",
-			"details": nil,
-		})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -72,13 +72,13 @@ func (h *UserHandler) RefreshToken(c echo.Context) error {
 		})
 	}
 
-	user, newToken, err := h.userService.RefreshToken(req.RefreshToken, sessionId)
+	user, newToken, err := h.userService.RefreshToken(c.Request().Context(), req.RefreshToken, sessionId)
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"message": "refresh failed - internal server error",
-			"details": nil,
-		})
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			metrics.RefreshTokenReuse.Inc()
+		}
+
+		return err
 	}
 
 	return c.JSON(http.StatusAccepted, map[string]interface{}{
@@ -92,6 +92,7 @@ func (h *UserHandler) RefreshToken(c echo.Context) error {
 
 type loginRequest struct {
 	Username string `json:"username" validate:"required"`
+	Password string `json:"password"`
 }
 
 func (h *UserHandler) Login(c echo.Context) error {
@@ -118,21 +119,22 @@ func (h *UserHandler) Login(c echo.Context) error {
 		})
 	}
 
-	user, token, err := h.userService.Authenticate(req.Username)
+	user, token, err := h.userService.Authenticate(c.Request().Context(), req.Username, req.Password)
 	if err != nil {
-		log.Println(err)
 		// not revealing whether a user is registered or not: CWE-204 CWE-203 OWASP A07:2021
-		if err.Error() == "user not found" || err.Error() == "unauthorize" {
-			return c.JSON(http.StatusUnauthorized, map[string]interface{}{
-				"message": "invalid username or password",
-				"details": nil,
-			})
-		} else {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"message": "login failed - internal server error",
-				"details": nil,
-			})
+		var locked *services.LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			metrics.LoginFailures.WithLabelValues("locked").Inc()
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		case errors.Is(err, repositories.ErrUserNotFound):
+			metrics.LoginFailures.WithLabelValues("unknown_user").Inc()
+			return services.ErrInvalidCredentials
+		case errors.Is(err, services.ErrInvalidCredentials):
+			metrics.LoginFailures.WithLabelValues("invalid_credentials").Inc()
 		}
+
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -179,13 +181,9 @@ func (h *UserHandler) UpdateUserTimezone(c echo.Context) error {
 		})
 	}
 
-	err := h.userService.UpdateUserTimezone(userId, req.Timezone)
+	err := h.userService.UpdateUserTimezone(c.Request().Context(), userId, req.Timezone)
 	if err != nil {
-		log.Println(err)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"message": "failed update timezone - internal server error",
-			"details": nil,
-		})
+		return err
 	}
 
 	return c.JSON(http.StatusAccepted, map[string]interface{}{
@@ -193,3 +191,50 @@ func (h *UserHandler) UpdateUserTimezone(c echo.Context) error {
 		"data":    nil,
 	})
 }
+
+type reqChangeBuffers struct {
+	BufferBeforeMinutes int `json:"buffer_before_minutes" validate:"min=0,max=240"`
+	BufferAfterMinutes  int `json:"buffer_after_minutes" validate:"min=0,max=240"`
+}
+
+func (h *UserHandler) UpdateUserBuffers(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+	var req reqChangeBuffers
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			fieldName, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				fieldName: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	err := h.userService.UpdateUserBuffers(c.Request().Context(), userId, req.BufferBeforeMinutes, req.BufferAfterMinutes)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message": "buffers updated",
+		"data":    req,
+	})
+}
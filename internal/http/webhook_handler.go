@@ -0,0 +1,163 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type WebhookHandler struct {
+	webhookService services.WebhookService
+}
+
+func NewWebhookHandler(webhookService services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+type webhookEndpointRequest struct {
+	Url        string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1"`
+}
+
+func (h *WebhookHandler) CreateEndpoint(c echo.Context) error {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid token or malformed token",
+			"details": nil,
+		})
+	}
+
+	var req webhookEndpointRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		var validationErrors []map[string]string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			fieldName, friendlyMessage := utils.GetFriendlyErrorMessage(e, req)
+
+			validationErrors = append(validationErrors, map[string]string{
+				fieldName: friendlyMessage,
+			})
+		}
+
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "bad request - validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	endpoint, err := h.webhookService.CreateEndpoint(c.Request().Context(), userId, req.Url, req.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "webhook endpoint created, store the secret now as it is not shown again",
+		"data":    endpoint,
+	})
+}
+
+func (h *WebhookHandler) GetEndpoints(c echo.Context) error {
+	endpoints, err := h.webhookService.GetEndpoints(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    endpoints,
+	})
+}
+
+func (h *WebhookHandler) DeactivateEndpoint(c echo.Context) error {
+	endpointId, err := strconv.Atoi(c.Param("webhookId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid webhook id", "detail": nil})
+	}
+
+	err = h.webhookService.DeactivateEndpoint(c.Request().Context(), endpointId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "webhook endpoint deactivated",
+		"data":    nil,
+	})
+}
+
+func (h *WebhookHandler) GetDeliveries(c echo.Context) error {
+	endpointId, err := strconv.Atoi(c.Param("webhookId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid webhook id", "detail": nil})
+	}
+
+	limit, offset := 50, 0
+	if param := c.QueryParam("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > 500 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "limit must be between 1 and 500", "detail": nil})
+		}
+	}
+	if param := c.QueryParam("offset"); param != "" {
+		offset, err = strconv.Atoi(param)
+		if err != nil || offset < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid offset", "detail": nil})
+		}
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(c.Request().Context(), endpointId, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    deliveries,
+	})
+}
+
+func (h *WebhookHandler) GetDelivery(c echo.Context) error {
+	deliveryId, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid delivery id", "detail": nil})
+	}
+
+	delivery, err := h.webhookService.GetDelivery(c.Request().Context(), deliveryId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ok",
+		"data":    delivery,
+	})
+}
+
+func (h *WebhookHandler) ReplayDelivery(c echo.Context) error {
+	deliveryId, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "Invalid delivery id", "detail": nil})
+	}
+
+	err = h.webhookService.ReplayDelivery(c.Request().Context(), deliveryId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message": "webhook delivery queued",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "appointment"
+
+var (
+	// HTTPRequestDuration is labelled with the route pattern, not the path,
+	// to keep ids out of the label values.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	RedisCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_duration_seconds",
+		Help:      "Latency of Redis commands by command and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command", "status"})
+
+	AppointmentsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "appointments_created_total",
+		Help:      "Appointments created, by source (api or booking page).",
+	}, []string{"source"})
+
+	InvitationResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invitation_responses_total",
+		Help:      "Invitation responses by resulting status (accepted, waitlisted or rejected).",
+	}, []string{"status"})
+
+	LoginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed sign-ins by reason (unknown_user, invalid_credentials or locked).",
+	}, []string{"reason"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by a rate limit policy.",
+	}, []string{"policy"})
+
+	// ListCacheRequests gives the hit rate of the list cache, per list.
+	ListCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "List cache lookups by list (appointments or invitations) and result (hit or miss).",
+	}, []string{"list", "result"})
+
+	RefreshTokenReuse = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_token_reuse_total",
+		Help:      "Refresh tokens refused because they were presented again after being rotated.",
+	})
+)
+
+// Handler serves the metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, namespace))
+}
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -11,7 +10,7 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-func AuthMiddleware(redisRepo repositories.RedisRepository) echo.MiddlewareFunc {
+func AuthMiddleware(redisRepo repositories.RedisRepository, tokenIssuer *utils.TokenIssuer) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -25,7 +24,7 @@ func AuthMiddleware(redisRepo repositories.RedisRepository) echo.MiddlewareFunc
 			}
 
 			tokenString := parts[1]
-			token, err := utils.VerifyToken(tokenString, false)
+			token, err := tokenIssuer.VerifyToken(tokenString, false)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired token"})
 			}
@@ -40,7 +39,7 @@ func AuthMiddleware(redisRepo repositories.RedisRepository) echo.MiddlewareFunc
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token claims"})
 			}
 
-			dataJSON, err := redisRepo.Get(context.Background(), sessionId)
+			dataJSON, err := redisRepo.Get(c.Request().Context(), sessionId)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired session"})
 			}
@@ -55,8 +54,11 @@ func AuthMiddleware(redisRepo repositories.RedisRepository) echo.MiddlewareFunc
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token claims"})
 			}
 
+			role, _ := dataUser["role"].(string)
+
 			c.Set("userId", int(userId))
 			c.Set("sessionId", sessionId)
+			c.Set("role", role)
 
 			return next(c)
 		}
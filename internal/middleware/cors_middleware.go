@@ -11,7 +11,8 @@ func CORSMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 
 		c.Response().Header().Set("Access-Control-Allow-Origin", "*")
 		c.Response().Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, PUT, DELETE, OPTIONS")
-		c.Response().Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Client-Key")
+		c.Response().Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Client-Key, X-Request-ID, Idempotency-Key, If-Match, If-None-Match")
+		c.Response().Header().Set("Access-Control-Expose-Headers", "X-Request-ID, ETag, Idempotent-Replayed, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		if c.Request().Method == http.MethodOptions {
 			return c.NoContent(http.StatusNoContent)
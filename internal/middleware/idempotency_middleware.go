@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+var errIdempotencyKeyTooLong = apperror.Validation("Idempotency-Key must be at most 255 characters")
+
+// IdempotencyMiddleware makes POST and PATCH requests that carry an
+// Idempotency-Key safe to retry. The first request is handled and its
+// response kept; a retry with the same key and payload gets that response
+// again, with Idempotent-Replayed set. A retry while the first is still
+// running gets 409, the same key with a different payload 422.
+//
+// Keys are scoped by the user set by AuthMiddleware, which must run first, so
+// a retry with a refreshed token still replays. Anonymous requests are scoped
+// by client IP. Server errors and rate limited responses are not kept, their retries run
+// again. When Redis fails the request is handled without the key.
+func IdempotencyMiddleware(idempotencyService services.IdempotencyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if key == "" || (req.Method != http.MethodPost && req.Method != http.MethodPatch) {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return errIdempotencyKeyTooLong
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return apperror.Wrap(apperror.KindValidation, "Invalid request", err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			key = idempotencyScope(c) + ":" + key
+			fingerprint := requestFingerprint(req, body)
+
+			ctx := req.Context()
+			record, err := idempotencyService.Begin(ctx, key, fingerprint)
+			if apperror.From(err) != nil {
+				return err
+			}
+			if err != nil {
+				slog.WarnContext(ctx, "idempotency unavailable", "error", err.Error())
+				return next(c)
+			}
+
+			if record != nil {
+				c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+				if record.ETag != "" {
+					c.Response().Header().Set("ETag", record.ETag)
+				}
+				return c.Blob(record.Status, record.ContentType, record.Body)
+			}
+
+			recorder := &bodyRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			res := c.Response()
+			if res.Status >= http.StatusInternalServerError || res.Status == http.StatusTooManyRequests {
+				err = idempotencyService.Release(ctx, key)
+			} else {
+				err = idempotencyService.Complete(ctx, key, &models.IdempotencyRecord{
+					Fingerprint: fingerprint,
+					Status:      res.Status,
+					ContentType: res.Header().Get(echo.HeaderContentType),
+					ETag:        res.Header().Get("ETag"),
+					Body:        recorder.body.Bytes(),
+				})
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "storing idempotent response", "error", err.Error())
+			}
+
+			return nil
+		}
+	}
+}
+
+func idempotencyScope(c echo.Context) string {
+	if userId, ok := c.Get("userId").(int); ok {
+		return "user:" + strconv.Itoa(userId)
+	}
+
+	return "ip:" + c.RealIP()
+}
+
+func requestFingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+" "+req.URL.RequestURI()+"\n")
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// bodyRecorder keeps a copy of what is written to the client.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LoggingMiddleware writes one structured record per request. Errors of the
+// handler are rendered first, so the record has the status that was sent.
+func LoggingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		req := c.Request()
+		res := c.Response()
+
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("route", c.Path()),
+			slog.String("path", req.URL.Path),
+			slog.Int("status", res.Status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int64("bytes", res.Size),
+			slog.String("remote_ip", c.RealIP()),
+		}
+
+		if userId, ok := c.Get("userId").(int); ok {
+			attrs = append(attrs, slog.Int("user_id", userId))
+		}
+		if sessionId, ok := c.Get("sessionId").(string); ok {
+			attrs = append(attrs, slog.String("session_id", sessionId))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case res.Status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case res.Status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		slog.LogAttrs(req.Context(), level, "request", attrs...)
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// MetricsMiddleware records the latency and status of every request under
+// its route pattern. Requests matching no route share the "unmatched" route.
+func MetricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		status := c.Response().Status
+		if err != nil && !c.Response().Committed {
+			status = http.StatusInternalServerError
+
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			}
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// QueryTokenMiddleware lets clients that cannot set headers, like the browser
+// EventSource, pass the access token as ?access_token. It must be placed
+// before AuthMiddleware.
+func QueryTokenMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if token := c.QueryParam("access_token"); token != "" && c.Request().Header.Get("Authorization") == "" {
+			c.Request().Header.Set("Authorization", "Bearer "+token)
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitKey names the client a request is counted against, an empty key
+// skips the request.
+type RateLimitKey func(c echo.Context) string
+
+// ClientIPExtractor resolves the client address ByIP counts against. It
+// trusts X-Forwarded-For only from proxies on loopback or private networks,
+// like the ingress, so clients cannot pick their own address.
+func ClientIPExtractor() echo.IPExtractor {
+	return echo.ExtractIPFromXFFHeader()
+}
+
+// ByIP counts requests per client address, see ClientIPExtractor.
+func ByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// ByUserId counts requests per signed-in user. It must be placed after
+// AuthMiddleware.
+func ByUserId(c echo.Context) string {
+	userId, ok := c.Get("userId").(int)
+	if !ok {
+		return ""
+	}
+
+	return "user:" + strconv.Itoa(userId)
+}
+
+// peekLimit bounds how much of a body ByUsername reads.
+const peekLimit = 64 << 10
+
+// ByUsername counts requests per "username" of a JSON body, e.g. sign-ins.
+// The body is put back for the handler.
+func ByUsername(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil {
+		return ""
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(req.Body, peekLimit))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), req.Body), req.Body}
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Username string `json:"username"`
+	}
+	if json.Unmarshal(peeked, &body) != nil || body.Username == "" {
+		return ""
+	}
+
+	return "username:" + strings.ToLower(body.Username)
+}
+
+// RateLimitMiddleware applies the named policy to every client key of the
+// request and answers 429 once one of them is over the limit. The
+// RateLimit-* headers describe the most restricted key. When Redis fails the
+// request is let through, throttling is not worth an outage.
+func RateLimitMiddleware(limiter services.RateLimitService, name string, policy models.RateLimitPolicy, keys ...RateLimitKey) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if policy.Limit <= 0 {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+
+			var tightest *models.RateLimit
+			for _, key := range keys {
+				client := key(c)
+				if client == "" {
+					continue
+				}
+
+				limit, err := limiter.Allow(ctx, name, client, policy)
+				if err != nil {
+					slog.WarnContext(ctx, "rate limit unavailable", "policy", name, "error", err.Error())
+					return next(c)
+				}
+
+				if tightest == nil || !limit.Allowed || (tightest.Allowed && limit.Remaining < tightest.Remaining) {
+					tightest = limit
+				}
+				if !limit.Allowed {
+					break
+				}
+			}
+
+			if tightest == nil {
+				return next(c)
+			}
+
+			reset := strconv.Itoa(int(math.Ceil(tightest.Reset.Seconds())))
+			header := c.Response().Header()
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds())))
+			header.Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+			header.Set("RateLimit-Reset", reset)
+
+			if !tightest.Allowed {
+				metrics.RateLimited.WithLabelValues(name).Inc()
+				header.Set(echo.HeaderRetryAfter, reset)
+				return apperror.RateLimited("too many requests, try again later")
+			}
+
+			return next(c)
+		}
+	}
+}
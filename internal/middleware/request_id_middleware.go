@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+// RequestIdMiddleware reuses a well formed X-Request-ID from the caller or
+// generates one, echoes it in the response and puts it in the request context.
+func RequestIdMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestId := c.Request().Header.Get(echo.HeaderXRequestID)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = utils.NewRequestId()
+		}
+
+		c.Response().Header().Set(echo.HeaderXRequestID, requestId)
+		c.SetRequest(c.Request().WithContext(utils.WithRequestId(c.Request().Context(), requestId)))
+
+		return next(c)
+	}
+}
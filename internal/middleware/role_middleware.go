@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RoleMiddleware only lets through users whose session role is one of roles.
+// It must be placed after AuthMiddleware.
+func RoleMiddleware(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("role").(string)
+
+			for _, allowed := range roles {
+				if role == allowed {
+					return next(c)
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]string{"message": "Insufficient permission"})
+		}
+	}
+}
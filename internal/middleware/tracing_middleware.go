@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/tracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware continues the trace of an incoming W3C traceparent header
+// or starts a new one, and runs the request under a server span named after
+// the route. Handlers pass the request context down to the repositories.
+func TracingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx, span := tracing.Tracer().Start(ctx, req.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.HTTPRoute(route),
+				attribute.String("url.path", req.URL.Path),
+			),
+		)
+		defer span.End()
+
+		if requestId := c.Response().Header().Get(echo.HeaderXRequestID); requestId != "" {
+			span.SetAttributes(attribute.String("request.id", requestId))
+		}
+
+		c.SetRequest(req.WithContext(ctx))
+		err := next(c)
+
+		status := c.Response().Status
+		if err != nil && !c.Response().Committed {
+			status = http.StatusInternalServerError
+
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			}
+		}
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if err != nil {
+			span.RecordError(err)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		return err
+	}
+}
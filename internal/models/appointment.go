@@ -2,23 +2,124 @@ package models
 
 import "time"
 
+const (
+	AppointmentStatusTentative = "tentative"
+	AppointmentStatusConfirmed = "confirmed"
+	AppointmentStatusAtRisk    = "at-risk"
+	AppointmentStatusCancelled = "cancelled"
+)
+
 type Appointment struct {
-	AppointmentId int       `json:"appointment_id"`
-	HostId        int       `json:"host_id"`
-	Title         string    `json:"title"`
-	StartTime     time.Time `json:"start_time"`
-	EndTime       time.Time `json:"end_time"`
-	CreatedAt     time.Time `json:"created_at"`
-	InviteeIds    []int     `json:"invitee_ids,omitempty"`
+	AppointmentId      int        `json:"appointment_id"`
+	HostId             int        `json:"host_id"`
+	Title              string     `json:"title"`
+	Description        string     `json:"description"`
+	Location           string     `json:"location"`
+	TravelMinutes      int        `json:"travel_minutes"`
+	MeetingProvider    string     `json:"meeting_provider,omitempty"`
+	JoinUrl            string     `json:"join_url,omitempty"`
+	VideoMeeting       bool       `json:"-"`
+	StartTime          time.Time  `json:"start_time"`
+	EndTime            time.Time  `json:"end_time"`
+	Quorum             *int       `json:"quorum"`
+	IsOpen             bool       `json:"is_open"`
+	Capacity           *int       `json:"capacity"`
+	EventTypeId        *int       `json:"event_type_id,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	Version            int        `json:"version"`
+	Tags               []string   `json:"tags,omitempty"`
+	InviteeIds         []int      `json:"invitee_ids,omitempty"`
+	OptionalInviteeIds []int      `json:"optional_invitee_ids,omitempty"`
+	FyiInviteeIds      []int      `json:"fyi_invitee_ids,omitempty"`
+	ResourceIds        []int      `json:"resource_ids,omitempty"`
+	Guests             []Guest    `json:"guests,omitempty"`
+	Warnings           []string   `json:"warnings,omitempty"`
+}
+
+// AppointmentPatch holds the appointment fields a host changes, nil fields are kept.
+type AppointmentPatch struct {
+	Title         *string
+	Description   *string
+	Location      *string
+	TravelMinutes *int
+	StartTime     *time.Time
+	EndTime       *time.Time
+	Tags          *[]string
 }
 
 type AppointmentInvitation struct {
 	Appointment
-	TotalAttendants int    `json:"total_attendants"`
-	InvitationId    int    `json:"invitation_id"`
-	Invitee_id      int    `json:"invitee_id"`
-	Status          string `json:"status"`
-	Notes           string `json:"notes"`
-	Host            User   `json:"host"`
-	Attendants      []User `json:"attendants"`
+	TotalAttendants    int               `json:"total_attendants"`
+	RequiredAttendance AttendanceSummary `json:"required_attendance"`
+	AppointmentStatus  string            `json:"appointment_status"`
+	InvitationId       int               `json:"invitation_id"`
+	InvitationVersion  int               `json:"invitation_version"`
+	Invitee_id         int               `json:"invitee_id"`
+	Role               string            `json:"role"`
+	Status             string            `json:"status"`
+	Notes              string            `json:"notes"`
+	Host               User              `json:"host"`
+	Attendants         []User            `json:"attendants"`
+}
+
+// AppointmentSearch filters the appointments a user takes part in. Query is
+// full-text in web search syntax on the title and description, Tags must all
+// be present, From and To select appointments overlapping the range.
+type AppointmentSearch struct {
+	Query         string
+	HostId        *int
+	ParticipantId *int
+	Status        string
+	From          time.Time
+	To            time.Time
+	Tags          []string
+	ResourceId    *int
+	Limit         int
+	Offset        int
+}
+
+// AppointmentSearchResult is a found appointment with its derived status and
+// its relevance to the query, 0 without one.
+type AppointmentSearchResult struct {
+	Appointment
+	AppointmentStatus string  `json:"appointment_status"`
+	Rank              float64 `json:"rank"`
+}
+
+// OpenAppointment is an appointment users can self-join, with its current occupancy.
+type OpenAppointment struct {
+	Appointment
+	AcceptedCount int    `json:"accepted_count"`
+	WaitlistCount int    `json:"waitlist_count"`
+	Status        string `json:"status"`
+	Host          User   `json:"host"`
+}
+
+// AttendanceSummary counts invitation statuses of the required attendees.
+type AttendanceSummary struct {
+	Total    int `json:"total"`
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+	Pending  int `json:"pending"`
+}
+
+// DeriveAppointmentStatus computes the appointment status from required attendance.
+// Without a quorum every required attendee has to accept; with a quorum the
+// appointment is confirmed once enough required attendees accept and at risk
+// when the quorum can no longer be reached.
+func DeriveAppointmentStatus(quorum *int, summary AttendanceSummary) string {
+	needed := summary.Total
+	if quorum != nil {
+		needed = *quorum
+	}
+
+	switch {
+	case summary.Accepted >= needed:
+		return AppointmentStatusConfirmed
+	case summary.Accepted+summary.Pending < needed:
+		return AppointmentStatusAtRisk
+	default:
+		return AppointmentStatusTentative
+	}
 }
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditActionAppointmentCreated     = "appointment.created"
+	AuditActionAppointmentUpdated     = "appointment.updated"
+	AuditActionAppointmentRescheduled = "appointment.rescheduled"
+	AuditActionAppointmentCancelled   = "appointment.cancelled"
+	AuditActionInviteeAdded           = "invitee.added"
+	AuditActionInviteeRemoved         = "invitee.removed"
+	AuditActionInvitationStatus       = "invitation.status_changed"
+)
+
+const (
+	AuditEntityAppointment = "appointment"
+	AuditEntityInvitation  = "invitation"
+)
+
+// AuditEvent is an immutable record of a change. Before and After only hold
+// the fields that changed. ActorId is nil for changes made by external guests
+// or by the system, e.g. a waitlist promotion.
+type AuditEvent struct {
+	AuditEventId  int64           `json:"audit_event_id"`
+	AppointmentId int             `json:"appointment_id"`
+	EntityType    string          `json:"entity_type"`
+	EntityId      int             `json:"entity_id"`
+	Action        string          `json:"action"`
+	ActorId       *int            `json:"actor_id"`
+	RequestId     string          `json:"request_id"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type AuditFilter struct {
+	AppointmentId *int
+	ActorId       *int
+	Action        string
+	From          time.Time
+	To            time.Time
+	Limit         int
+	Offset        int
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	BusyKindAppointment = "appointment"
+	BusyKindTimeOff     = "time_off"
+	BusyKindHoliday     = "holiday"
+)
+
+// BusyInterval is a period a user is unavailable. Appointments are already
+// widened by the appointment travel time and the user's buffers; the buffer
+// settings are kept to pad candidate appointments of the same user. Message
+// carries the auto-reply of time off and the name of holidays.
+type BusyInterval struct {
+	UserId        int       `json:"user_id"`
+	AppointmentId int       `json:"appointment_id"`
+	Kind          string    `json:"kind"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	Message       string    `json:"-"`
+
+	BufferBeforeMinutes int `json:"-"`
+	BufferAfterMinutes  int `json:"-"`
+}
+
+type TimeSlot struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+type AppointmentConflict struct {
+	UserId        int       `json:"user_id"`
+	Role          string    `json:"role"`
+	Kind          string    `json:"kind"`
+	AppointmentId int       `json:"appointment_id"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type BookingPage struct {
+	BookingPageId int         `json:"booking_page_id"`
+	HostId        int         `json:"host_id"`
+	Slug          string      `json:"slug"`
+	Title         string      `json:"title"`
+	Description   string      `json:"description"`
+	IsActive      bool        `json:"is_active"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Host          User        `json:"host"`
+	EventTypes    []EventType `json:"event_types"`
+}
+
+type EventType struct {
+	EventTypeId         int                  `json:"event_type_id"`
+	BookingPageId       int                  `json:"booking_page_id"`
+	Title               string               `json:"title"`
+	DurationMinutes     int                  `json:"duration_minutes"`
+	BufferBeforeMinutes int                  `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int                  `json:"buffer_after_minutes"`
+	MinNoticeMinutes    int                  `json:"min_notice_minutes"`
+	MaxPerDay           *int                 `json:"max_per_day"`
+	Availability        []AvailabilityWindow `json:"availability"`
+	CreatedAt           time.Time            `json:"created_at"`
+}
+
+// AvailabilityWindow is a weekly recurring window in the host timezone.
+// Weekday follows time.Weekday (0 = Sunday), Start and End use "15:04".
+type AvailabilityWindow struct {
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+type Guest struct {
+	GuestId       int       `json:"guest_id"`
+	AppointmentId int       `json:"appointment_id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	Notes         string    `json:"notes"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type EventTypeSlots struct {
+	EventType EventType  `json:"event_type"`
+	Timezone  string     `json:"timezone"`
+	Slots     []TimeSlot `json:"slots"`
+}
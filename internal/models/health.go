@@ -0,0 +1,24 @@
+package models
+
+const (
+	HealthStatusUp       = "up"
+	HealthStatusDegraded = "degraded"
+	HealthStatusDown     = "down"
+	HealthStatusDraining = "draining"
+)
+
+// HealthCheck is the outcome of probing one dependency.
+type HealthCheck struct {
+	Status    string                 `json:"status"`
+	LatencyMs float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// HealthReport is the readiness of the API. It is ready unless a check is
+// down or the process is draining, degraded checks are reported only.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Ready  bool                   `json:"ready"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
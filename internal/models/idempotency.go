@@ -0,0 +1,13 @@
+package models
+
+// IdempotencyRecord is what is kept of a request sent with an
+// Idempotency-Key. Fingerprint identifies the request. The response fields
+// are set once Completed.
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	ETag        string `json:"etag,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
@@ -2,11 +2,63 @@ package models
 
 import "time"
 
+const (
+	InvitationRoleRequired = "required"
+	InvitationRoleOptional = "optional"
+	InvitationRoleFyi      = "fyi"
+)
+
+const (
+	InvitationStatusPending    = "pending"
+	InvitationStatusAccepted   = "accepted"
+	InvitationStatusRejected   = "rejected"
+	InvitationStatusWaitlisted = "waitlisted"
+)
+
 type Invitation struct {
 	InvitationId  int       `json:"invitation_id"`
 	AppointmentId int       `json:"appointment_id"`
 	InviteeId     int       `json:"invitee_id"`
+	Role          string    `json:"role"`
 	Status        string    `json:"status"`
 	Notes         string    `json:"notes"`
 	CreatedAt     time.Time `json:"created_at"`
+	Version       int       `json:"version"`
+}
+
+// Attendee is an invitation of an appointment with the invited user.
+type Attendee struct {
+	InvitationId int       `json:"invitation_id"`
+	InviteeId    int       `json:"invitee_id"`
+	Username     string    `json:"username"`
+	Name         string    `json:"name"`
+	Timezone     string    `json:"timezone"`
+	Role         string    `json:"role"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AttendeeCounts counts the invitations of an appointment per status.
+type AttendeeCounts struct {
+	Total      int `json:"total"`
+	Accepted   int `json:"accepted"`
+	Pending    int `json:"pending"`
+	Rejected   int `json:"rejected"`
+	Waitlisted int `json:"waitlisted"`
+}
+
+type AttendeeFilter struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// AttendeePage is one page of attendees. Total is the number of attendees
+// matching the filter, Counts covers the whole appointment.
+type AttendeePage struct {
+	Attendees []Attendee     `json:"attendees"`
+	Counts    AttendeeCounts `json:"counts"`
+	Total     int            `json:"total"`
+	Limit     int            `json:"limit"`
+	Offset    int            `json:"offset"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job is a unit of background work run by the worker process.
+type Job struct {
+	JobId       string          `json:"job_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	UniqueKey   string          `json:"unique_key,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// JobOptions tune a single enqueue. A unique key keeps a second job with the
+// same key out of the queue until the first one finished or died.
+type JobOptions struct {
+	Delay       time.Duration
+	MaxAttempts int
+	UniqueKey   string
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a side effect of a committed change, waiting to be handed to
+// the outbox relay handlers. Event types are the webhook event types plus
+// RealtimeInvitationReceived, which is only pushed to clients.
+type OutboxEvent struct {
+	OutboxEventId int64           `json:"outbox_event_id"`
+	EventType     string          `json:"event_type"`
+	AppointmentId int             `json:"appointment_id"`
+	Recipients    []int           `json:"recipients"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RateLimitPolicy allows Limit requests per sliding Window. A zero Limit
+// turns the policy off.
+type RateLimitPolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimit is the state of one client under a policy after a request.
+// Reset is how long until the oldest counted request leaves the window.
+type RateLimit struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+// LockoutPolicy locks a username after Threshold failed sign-ins within
+// Window. Each further lockout of the same username doubles Duration, up to
+// MaxDuration.
+type LockoutPolicy struct {
+	Threshold   int
+	Window      time.Duration
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
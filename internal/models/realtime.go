@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RealtimeInvitationReceived   = "invitation.received"
+	RealtimeInvitationUpdated    = "invitation.updated"
+	RealtimeInvitationRevoked    = "invitation.revoked"
+	RealtimeAppointmentCreated   = "appointment.created"
+	RealtimeAppointmentUpdated   = "appointment.updated"
+	RealtimeAppointmentCancelled = "appointment.cancelled"
+)
+
+// RealtimeEvent is pushed to the connected clients of its recipients.
+type RealtimeEvent struct {
+	Type          string          `json:"type"`
+	AppointmentId int             `json:"appointment_id"`
+	Data          json.RawMessage `json:"data"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	ResourceTypeRoom      = "room"
+	ResourceTypeEquipment = "equipment"
+	ResourceTypeVehicle   = "vehicle"
+)
+
+type Resource struct {
+	ResourceId int       `json:"resource_id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Capacity   *int      `json:"capacity"`
+	Location   string    `json:"location"`
+	Timezone   string    `json:"timezone"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ResourceBooking struct {
+	AppointmentId int       `json:"appointment_id"`
+	Title         string    `json:"title"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	Host          User      `json:"host"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type TimeOff struct {
+	TimeOffId int       `json:"time_off_id"`
+	UserId    int       `json:"user_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Reason    string    `json:"reason"`
+	AutoReply string    `json:"auto_reply"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type HolidayCalendar struct {
+	HolidayCalendarId int       `json:"holiday_calendar_id"`
+	Name              string    `json:"name"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Holiday spans whole days, EndDate is exclusive.
+type Holiday struct {
+	HolidayId         int       `json:"holiday_id"`
+	HolidayCalendarId int       `json:"holiday_calendar_id"`
+	Uid               string    `json:"uid"`
+	Name              string    `json:"name"`
+	StartDate         time.Time `json:"start_date"`
+	EndDate           time.Time `json:"end_date"`
+	CreatedAt         time.Time `json:"created_at"`
+}
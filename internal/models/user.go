@@ -2,6 +2,11 @@ package models
 
 import "time"
 
+const (
+	UserRoleAdmin = "admin"
+	UserRoleStaff = "staff"
+)
+
 type User struct {
 	UserId    int       `json:"user_id"`
 	Name      string    `json:"name"`
@@ -11,6 +16,11 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	DeletedAt time.Time `json:"deleted_at"`
+
+	BufferBeforeMinutes int `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int `json:"buffer_after_minutes"`
+
+	PasswordHash string `json:"-"`
 }
 
 type JwtToken struct {
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	WebhookEventAppointmentCreated   = "appointment.created"
+	WebhookEventAppointmentUpdated   = "appointment.updated"
+	WebhookEventAppointmentCancelled = "appointment.cancelled"
+	WebhookEventInvitationCreated    = "invitation.created"
+	WebhookEventInvitationAccepted   = "invitation.accepted"
+	WebhookEventInvitationRejected   = "invitation.rejected"
+	WebhookEventInvitationWaitlisted = "invitation.waitlisted"
+	WebhookEventInvitationRevoked    = "invitation.revoked"
+)
+
+// WebhookEventTypes lists the event types endpoints can subscribe to.
+var WebhookEventTypes = []string{
+	WebhookEventAppointmentCreated,
+	WebhookEventAppointmentUpdated,
+	WebhookEventAppointmentCancelled,
+	WebhookEventInvitationCreated,
+	WebhookEventInvitationAccepted,
+	WebhookEventInvitationRejected,
+	WebhookEventInvitationWaitlisted,
+	WebhookEventInvitationRevoked,
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint is a receiver registered by an admin. The secret is only
+// returned when the endpoint is created.
+type WebhookEndpoint struct {
+	WebhookEndpointId int       `json:"webhook_endpoint_id"`
+	Url               string    `json:"url"`
+	Secret            string    `json:"secret,omitempty"`
+	EventTypes        []string  `json:"event_types"`
+	IsActive          bool      `json:"is_active"`
+	CreatedBy         int       `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	WebhookDeliveryId int64                    `json:"webhook_delivery_id"`
+	WebhookEndpointId int                      `json:"webhook_endpoint_id"`
+	EventType         string                   `json:"event_type"`
+	Payload           json.RawMessage          `json:"payload"`
+	Status            string                   `json:"status"`
+	Attempts          int                      `json:"attempts"`
+	NextAttemptAt     time.Time                `json:"next_attempt_at"`
+	LastResponseCode  *int                     `json:"last_response_code"`
+	LastError         string                   `json:"last_error"`
+	DeliveredAt       *time.Time               `json:"delivered_at"`
+	CreatedAt         time.Time                `json:"created_at"`
+	AttemptLog        []WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+
+	// filled when claimed for sending
+	Url    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ResponseCode *int      `json:"response_code"`
+	Error        string    `json:"error"`
+	DurationMs   int       `json:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
+
+// WebhookPayload is the JSON body posted to endpoints.
+type WebhookPayload struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
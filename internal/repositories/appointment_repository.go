@@ -1,19 +1,43 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAppointmentNotFound = apperror.NotFound("appointment not found")
+	ErrAppointmentModified = apperror.PreconditionFailed("appointment was changed since it was read")
 )
 
 type AppointmentRepository interface {
-	InsertAppointment(tx *sql.Tx, appointment *models.Appointment) (*models.Appointment, error)
-	BeginAppointmentTx() (*sql.Tx, error)
+	InsertAppointment(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) (*models.Appointment, error)
+	BeginAppointmentTx(ctx context.Context) (*sql.Tx, error)
+
+	GetAppointmentsByUserId(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.AppointmentInvitation, error)
+	GetBusyIntervals(ctx context.Context, userIds []int, startDate, endDate time.Time) ([]models.BusyInterval, error)
+	GetBusyIntervalsTx(ctx context.Context, tx *sql.Tx, userIds []int, startDate, endDate time.Time) ([]models.BusyInterval, error)
 
-	GetAppointmentsByUserId(userId int, startDate, endDate time.Time) ([]models.AppointmentInvitation, error)
+	GetAppointmentForUser(ctx context.Context, userId, appointmentId int) (*models.Appointment, error)
+	UpdateMeetingLink(ctx context.Context, tx *sql.Tx, appointmentId int, provider, joinUrl string) error
+	UpdateAppointment(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) error
+	CancelAppointment(ctx context.Context, tx *sql.Tx, appointment *models.Appointment, cancelledAt time.Time) error
+	BumpAppointmentVersion(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) error
+
+	LockAppointment(ctx context.Context, tx *sql.Tx, appointmentId int) (*models.Appointment, error)
+	LockSchedules(ctx context.Context, tx *sql.Tx, userIds []int) error
+	GetOpenAppointments(ctx context.Context, userId int, from time.Time) ([]models.OpenAppointment, error)
+
+	SearchAppointments(ctx context.Context, userId int, filter models.AppointmentSearch) ([]models.AppointmentSearchResult, error)
 }
 
 type appointmentRepository struct {
@@ -24,23 +48,25 @@ func NewAppointmentRepository(db *sql.DB) AppointmentRepository {
 	return &appointmentRepository{db: db}
 }
 
-func (r *appointmentRepository) BeginAppointmentTx() (*sql.Tx, error) {
-	return r.db.Begin()
+func (r *appointmentRepository) BeginAppointmentTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
 }
 
-func (r *appointmentRepository) InsertAppointment(tx *sql.Tx, appointment *models.Appointment) (*models.Appointment, error) {
+func (r *appointmentRepository) InsertAppointment(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) (*models.Appointment, error) {
 	query := `
-		INSERT INTO stg_appointment.appointments
-			(host_id, title, start_time, end_time, created_at)
+		INSERT INTO appointments
+			(host_id, title, description, location, travel_minutes, start_time, end_time, quorum, is_open,
+			capacity, event_type_id, created_at, tags)
 		VALUES
-			($1, $2, $3, $4, $5)
-		RETURNING appointment_id;
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING appointment_id, version;
 	`
 
-	err := r.db.QueryRow(
-		query, appointment.HostId, appointment.Title, appointment.StartTime, appointment.EndTime,
-		appointment.CreatedAt,
-	).Scan(&appointment.AppointmentId)
+	err := tx.QueryRowContext(ctx,
+		query, appointment.HostId, appointment.Title, appointment.Description, appointment.Location, appointment.TravelMinutes,
+		appointment.StartTime, appointment.EndTime, appointment.Quorum, appointment.IsOpen, appointment.Capacity,
+		appointment.EventTypeId, appointment.CreatedAt, pq.Array(appointmentTags(appointment)),
+	).Scan(&appointment.AppointmentId, &appointment.Version)
 
 	if err != nil {
 		return nil, err
@@ -49,20 +75,27 @@ func (r *appointmentRepository) InsertAppointment(tx *sql.Tx, appointment *model
 	return appointment, nil
 }
 
-func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, endDate time.Time) ([]models.AppointmentInvitation, error) {
+func (r *appointmentRepository) GetAppointmentsByUserId(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.AppointmentInvitation, error) {
 	query := `
 		WITH user_tz AS (
 			SELECT timezone
-			FROM stg_appointment.users
+			FROM users
 			WHERE user_id = $1
 		),
 		appointment_details AS (
 			SELECT 
 				a.appointment_id,
 				a.title,
+				a.description,
+				a.location,
+				a.travel_minutes,
+				a.meeting_provider,
+				a.join_url,
+				a.quorum,
 				timezone((SELECT timezone FROM user_tz), a.start_time) AS start_time,
 				timezone((SELECT timezone FROM user_tz), a.end_time) AS end_time,
 				a.created_at AS appointment_created_at,
+				a.version,
 				a.host_id,
 				-- Host information
 				jsonb_build_object(
@@ -73,9 +106,21 @@ func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, e
 				-- Get total attendants count
 				(
 					SELECT COUNT(*)
-					FROM stg_appointment.invitations inv
+					FROM invitations inv
 					WHERE inv.appointment_id = a.appointment_id
 				) AS total_attendants,
+				-- Required attendance summary for the derived appointment status
+				(
+					SELECT jsonb_build_object(
+						'total', COUNT(*),
+						'accepted', COUNT(*) FILTER (WHERE inv.status = 'accepted'),
+						'rejected', COUNT(*) FILTER (WHERE inv.status = 'rejected'),
+						'pending', COUNT(*) FILTER (WHERE inv.status = 'pending')
+					)
+					FROM invitations inv
+					WHERE inv.appointment_id = a.appointment_id
+						AND inv.role = 'required'
+				) AS required_attendance,
 				-- Limited attendants list (only 3)
 				(
 					SELECT jsonb_agg(attendant_info)
@@ -85,23 +130,25 @@ func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, e
 							'name', u.name,
 							'timezone', u.timezone,
 							'status', inv.status,
+							'role', inv.role,
 							'invitation_id', inv.invitation_id,
 							'invitee_id', inv.invitee_id
 						) as attendant_info
-						FROM stg_appointment.invitations inv
-						JOIN stg_appointment.users u ON inv.invitee_id = u.user_id
+						FROM invitations inv
+						JOIN users u ON inv.invitee_id = u.user_id
 						WHERE inv.appointment_id = a.appointment_id
 						LIMIT 3
 					) limited_attendants
 				) AS limited_attendants
-			FROM stg_appointment.appointments a
-			JOIN stg_appointment.users host ON a.host_id = host.user_id
+			FROM appointments a
+			JOIN users host ON a.host_id = host.user_id
 			WHERE a.start_time BETWEEN $2 AND $3
+				AND a.cancelled_at IS NULL
 				AND (
 					a.host_id = $1  -- User is host
 					OR EXISTS (
 						SELECT 1 
-						FROM stg_appointment.invitations i 
+						FROM invitations i 
 						WHERE i.appointment_id = a.appointment_id 
 						AND i.invitee_id = $1
 						AND (
@@ -114,15 +161,25 @@ func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, e
 		SELECT 
 			ad.appointment_id,
 			ad.title,
+			ad.description,
+			ad.location,
+			ad.travel_minutes,
+			ad.meeting_provider,
+			ad.join_url,
+			ad.quorum,
 			ad.start_time,
 			ad.end_time,
 			ad.appointment_created_at,
+			ad.version,
 			ad.host,
 			ad.total_attendants,
+			ad.required_attendance,
 			COALESCE(ad.limited_attendants, '[]'::jsonb) as attendants,
 			-- Invitation details for the current user
 			COALESCE(i.invitation_id, 0) AS invitation_id,
+			COALESCE(i.version, 0) AS invitation_version,
 			COALESCE(i.invitee_id, ad.host_id) AS invitee_id,
+			COALESCE(i.role, 'required') AS role,
 			COALESCE(i.status, 
 				CASE 
 					WHEN ad.host_id = $1 THEN 'host'
@@ -131,13 +188,13 @@ func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, e
 			) AS status,
 			COALESCE(i.created_at, ad.appointment_created_at) AS invitation_created_at
 		FROM appointment_details ad
-		LEFT JOIN stg_appointment.invitations i ON 
+		LEFT JOIN invitations i ON 
 			ad.appointment_id = i.appointment_id 
 			AND i.invitee_id = $1
 		ORDER BY ad.start_time;
 	`
 
-	rows, err := r.db.Query(query, userId, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, userId, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("error querying appointments: %w", err)
 	}
@@ -147,20 +204,30 @@ func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, e
 
 	for rows.Next() {
 		var appointment models.AppointmentInvitation
-		var hostJSON, attendantsJSON []byte
-		var invitationID sql.NullInt64
+		var hostJSON, requiredJSON, attendantsJSON []byte
+		var invitationID, quorum sql.NullInt64
 
 		err := rows.Scan(
 			&appointment.AppointmentId,
 			&appointment.Title,
+			&appointment.Description,
+			&appointment.Location,
+			&appointment.TravelMinutes,
+			&appointment.MeetingProvider,
+			&appointment.JoinUrl,
+			&quorum,
 			&appointment.StartTime,
 			&appointment.EndTime,
 			&appointment.CreatedAt,
+			&appointment.Version,
 			&hostJSON,
 			&appointment.TotalAttendants,
+			&requiredJSON,
 			&attendantsJSON,
 			&invitationID,
+			&appointment.InvitationVersion,
 			&appointment.Invitee_id,
+			&appointment.Role,
 			&appointment.Status,
 			&appointment.CreatedAt,
 		)
@@ -180,6 +247,17 @@ func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, e
 			return nil, fmt.Errorf("error unmarshaling attendants data: %w", err)
 		}
 
+		if err := json.Unmarshal(requiredJSON, &appointment.RequiredAttendance); err != nil {
+			return nil, fmt.Errorf("error unmarshaling required attendance data: %w", err)
+		}
+
+		if quorum.Valid {
+			q := int(quorum.Int64)
+			appointment.Quorum = &q
+		}
+
+		appointment.AppointmentStatus = models.DeriveAppointmentStatus(appointment.Quorum, appointment.RequiredAttendance)
+
 		appointments = append(appointments, appointment)
 	}
 
@@ -189,3 +267,491 @@ func (r *appointmentRepository) GetAppointmentsByUserId(userId int, startDate, e
 
 	return appointments, nil
 }
+
+// queryer is either the pool or a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (r *appointmentRepository) GetBusyIntervals(ctx context.Context, userIds []int, startDate, endDate time.Time) ([]models.BusyInterval, error) {
+	return getBusyIntervals(ctx, r.db, userIds, startDate, endDate)
+}
+
+// GetBusyIntervalsTx reads the busy intervals in the transaction, for checks
+// made under LockSchedules.
+func (r *appointmentRepository) GetBusyIntervalsTx(ctx context.Context, tx *sql.Tx, userIds []int, startDate, endDate time.Time) ([]models.BusyInterval, error) {
+	return getBusyIntervals(ctx, tx, userIds, startDate, endDate)
+}
+
+func getBusyIntervals(ctx context.Context, q queryer, userIds []int, startDate, endDate time.Time) ([]models.BusyInterval, error) {
+	query := `
+		WITH user_appointments AS (
+			-- Appointments hosted by the users
+			SELECT a.host_id AS user_id, a.appointment_id, a.start_time, a.end_time, a.travel_minutes
+			FROM appointments a
+			WHERE a.host_id = ANY($1)
+				AND a.cancelled_at IS NULL
+				AND a.start_time < $3 + INTERVAL '1 day'
+				AND a.end_time > $2 - INTERVAL '1 day'
+			UNION ALL
+			-- Appointments the users have accepted
+			SELECT i.invitee_id AS user_id, a.appointment_id, a.start_time, a.end_time, a.travel_minutes
+			FROM appointments a
+			JOIN invitations i ON a.appointment_id = i.appointment_id
+			WHERE i.invitee_id = ANY($1)
+				AND i.status = 'accepted'
+				AND a.cancelled_at IS NULL
+				AND a.start_time < $3 + INTERVAL '1 day'
+				AND a.end_time > $2 - INTERVAL '1 day'
+		),
+		busy AS (
+			-- Travel time and the user's buffers are busy as well
+			SELECT
+				ua.user_id,
+				ua.appointment_id,
+				'appointment' AS kind,
+				ua.start_time - make_interval(mins => ua.travel_minutes + u.buffer_before_minutes) AS start_time,
+				ua.end_time + make_interval(mins => ua.travel_minutes + u.buffer_after_minutes) AS end_time,
+				'' AS message,
+				u.buffer_before_minutes,
+				u.buffer_after_minutes
+			FROM user_appointments ua
+			JOIN users u ON ua.user_id = u.user_id
+			UNION ALL
+			-- Personal time off
+			SELECT t.user_id, 0, 'time_off', t.start_time, t.end_time, t.auto_reply, 0, 0
+			FROM time_off t
+			WHERE t.user_id = ANY($1)
+			UNION ALL
+			-- Holidays of the user's calendars, whole days in the user's timezone
+			SELECT
+				u.user_id,
+				0,
+				'holiday',
+				h.start_date::timestamp AT TIME ZONE u.timezone,
+				h.end_date::timestamp AT TIME ZONE u.timezone,
+				h.name,
+				0,
+				0
+			FROM holidays h
+			JOIN holiday_calendar_users hcu ON hcu.holiday_calendar_id = h.holiday_calendar_id
+			JOIN users u ON u.user_id = hcu.user_id
+			WHERE hcu.user_id = ANY($1)
+				AND h.start_date <= $3::date + 1
+				AND h.end_date >= $2::date - 1
+		)
+		SELECT user_id, appointment_id, kind, start_time, end_time, message, buffer_before_minutes, buffer_after_minutes
+		FROM busy
+		WHERE start_time < $3
+			AND end_time > $2
+		ORDER BY start_time;
+	`
+
+	ids := make([]int64, 0, len(userIds))
+	for _, id := range userIds {
+		ids = append(ids, int64(id))
+	}
+
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids), startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error querying busy intervals: %w", err)
+	}
+	defer rows.Close()
+
+	var intervals []models.BusyInterval
+
+	for rows.Next() {
+		var interval models.BusyInterval
+		err := rows.Scan(
+			&interval.UserId, &interval.AppointmentId, &interval.Kind, &interval.StartTime, &interval.EndTime,
+			&interval.Message, &interval.BufferBeforeMinutes, &interval.BufferAfterMinutes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning busy interval row: %w", err)
+		}
+		intervals = append(intervals, interval)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating busy interval rows: %w", err)
+	}
+
+	return intervals, nil
+}
+
+// LockAppointment reads the appointment row with FOR UPDATE so concurrent
+// joins and status changes on the same appointment are serialized.
+func (r *appointmentRepository) LockAppointment(ctx context.Context, tx *sql.Tx, appointmentId int) (*models.Appointment, error) {
+	query := `
+		SELECT ` + appointmentColumns + `
+		FROM appointments
+		WHERE appointment_id = $1
+		FOR UPDATE;
+	`
+
+	return scanAppointment(tx.QueryRowContext(ctx, query, appointmentId))
+}
+
+// GetAppointmentForUser returns the appointment when the user hosts it or is
+// invited to it, so callers cannot read appointments they take no part in.
+func (r *appointmentRepository) GetAppointmentForUser(ctx context.Context, userId, appointmentId int) (*models.Appointment, error) {
+	query := `
+		SELECT ` + appointmentColumns + `
+		FROM appointments a
+		WHERE a.appointment_id = $1
+			AND (
+				a.host_id = $2
+				OR EXISTS (
+					SELECT 1
+					FROM invitations i
+					WHERE i.appointment_id = a.appointment_id
+						AND i.invitee_id = $2
+				)
+			);
+	`
+
+	return scanAppointment(r.db.QueryRowContext(ctx, query, appointmentId, userId))
+}
+
+// UpdateMeetingLink stores the join link generated by the meeting provider.
+func (r *appointmentRepository) UpdateMeetingLink(ctx context.Context, tx *sql.Tx, appointmentId int, provider, joinUrl string) error {
+	query := `
+		UPDATE appointments
+		SET meeting_provider = $2, join_url = $3
+		WHERE appointment_id = $1;
+	`
+
+	_, err := tx.ExecContext(ctx, query, appointmentId, provider, joinUrl)
+	return err
+}
+
+// UpdateAppointment writes the appointment if it is still at
+// appointment.Version and bumps the version, ErrAppointmentModified otherwise.
+func (r *appointmentRepository) UpdateAppointment(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) error {
+	query := `
+		UPDATE appointments
+		SET title = $2, description = $3, location = $4, travel_minutes = $5, start_time = $6, end_time = $7,
+			tags = $9, version = version + 1
+		WHERE appointment_id = $1 AND version = $8
+		RETURNING version;
+	`
+
+	err := tx.QueryRowContext(ctx,
+		query, appointment.AppointmentId, appointment.Title, appointment.Description, appointment.Location,
+		appointment.TravelMinutes, appointment.StartTime, appointment.EndTime, appointment.Version,
+		pq.Array(appointmentTags(appointment)),
+	).Scan(&appointment.Version)
+	if err == sql.ErrNoRows {
+		return ErrAppointmentModified
+	}
+	return err
+}
+
+// CancelAppointment cancels the appointment if it is still at
+// appointment.Version, like UpdateAppointment.
+func (r *appointmentRepository) CancelAppointment(ctx context.Context, tx *sql.Tx, appointment *models.Appointment, cancelledAt time.Time) error {
+	query := `
+		UPDATE appointments
+		SET cancelled_at = $2, version = version + 1
+		WHERE appointment_id = $1 AND version = $3
+		RETURNING version;
+	`
+
+	err := tx.QueryRowContext(ctx, query, appointment.AppointmentId, cancelledAt, appointment.Version).Scan(&appointment.Version)
+	if err == sql.ErrNoRows {
+		return ErrAppointmentModified
+	}
+	if err != nil {
+		return err
+	}
+
+	appointment.CancelledAt = &cancelledAt
+	return nil
+}
+
+// BumpAppointmentVersion records a change to the appointment made outside its
+// row, its invitees, if it is still at appointment.Version, like
+// UpdateAppointment.
+func (r *appointmentRepository) BumpAppointmentVersion(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) error {
+	query := `
+		UPDATE appointments
+		SET version = version + 1
+		WHERE appointment_id = $1 AND version = $2
+		RETURNING version;
+	`
+
+	err := tx.QueryRowContext(ctx, query, appointment.AppointmentId, appointment.Version).Scan(&appointment.Version)
+	if err == sql.ErrNoRows {
+		return ErrAppointmentModified
+	}
+	return err
+}
+
+const appointmentColumns = `appointment_id, host_id, title, description, location, travel_minutes, meeting_provider,
+			join_url, start_time, end_time, quorum, is_open, capacity, created_at, cancelled_at, version, tags`
+
+// rowScanner is either *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAppointment reads the appointmentColumns of a row, followed by the
+// columns of extra, if any.
+func scanAppointment(row rowScanner, extra ...interface{}) (*models.Appointment, error) {
+	var appointment models.Appointment
+	var quorum, capacity sql.NullInt64
+
+	dest := []interface{}{
+		&appointment.AppointmentId, &appointment.HostId, &appointment.Title, &appointment.Description,
+		&appointment.Location, &appointment.TravelMinutes, &appointment.MeetingProvider, &appointment.JoinUrl,
+		&appointment.StartTime, &appointment.EndTime, &quorum, &appointment.IsOpen, &capacity, &appointment.CreatedAt,
+		&appointment.CancelledAt, &appointment.Version, pq.Array(&appointment.Tags),
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAppointmentNotFound
+		}
+		return nil, err
+	}
+
+	if quorum.Valid {
+		q := int(quorum.Int64)
+		appointment.Quorum = &q
+	}
+
+	if capacity.Valid {
+		c := int(capacity.Int64)
+		appointment.Capacity = &c
+	}
+
+	return &appointment, nil
+}
+
+// LockSchedules takes a transaction scoped advisory lock on the schedule of
+// each user, so that everything putting them into an appointment validates
+// their free time one after another. Locks are taken in ascending user order
+// to keep concurrent callers from deadlocking.
+func (r *appointmentRepository) LockSchedules(ctx context.Context, tx *sql.Tx, userIds []int) error {
+	ids := append([]int(nil), userIds...)
+	sort.Ints(ids)
+
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('user_schedule'), $1);`, id)
+		if err != nil {
+			return fmt.Errorf("error lock schedule: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *appointmentRepository) GetOpenAppointments(ctx context.Context, userId int, from time.Time) ([]models.OpenAppointment, error) {
+	query := `
+		WITH user_tz AS (
+			SELECT timezone
+			FROM users
+			WHERE user_id = $1
+		)
+		SELECT
+			a.appointment_id,
+			a.host_id,
+			a.title,
+			a.description,
+			a.location,
+			a.join_url,
+			timezone((SELECT timezone FROM user_tz), a.start_time) AS start_time,
+			timezone((SELECT timezone FROM user_tz), a.end_time) AS end_time,
+			a.capacity,
+			a.created_at,
+			jsonb_build_object(
+				'username', host.username,
+				'name', host.name,
+				'timezone', host.timezone
+			) AS host,
+			COUNT(inv.invitation_id) FILTER (WHERE inv.status = 'accepted') AS accepted_count,
+			COUNT(inv.invitation_id) FILTER (WHERE inv.status = 'waitlisted') AS waitlist_count,
+			COALESCE(MAX(inv.status) FILTER (WHERE inv.invitee_id = $1), '') AS status
+		FROM appointments a
+		JOIN users host ON a.host_id = host.user_id
+		LEFT JOIN invitations inv ON a.appointment_id = inv.appointment_id
+		WHERE a.is_open
+			AND a.cancelled_at IS NULL
+			AND a.start_time >= $2
+		GROUP BY a.appointment_id, host.user_id
+		ORDER BY a.start_time;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userId, from)
+	if err != nil {
+		return nil, fmt.Errorf("error querying open appointments: %w", err)
+	}
+	defer rows.Close()
+
+	var appointments []models.OpenAppointment
+
+	for rows.Next() {
+		var appointment models.OpenAppointment
+		var hostJSON []byte
+		var capacity sql.NullInt64
+
+		err := rows.Scan(
+			&appointment.AppointmentId,
+			&appointment.HostId,
+			&appointment.Title,
+			&appointment.Description,
+			&appointment.Location,
+			&appointment.JoinUrl,
+			&appointment.StartTime,
+			&appointment.EndTime,
+			&capacity,
+			&appointment.CreatedAt,
+			&hostJSON,
+			&appointment.AcceptedCount,
+			&appointment.WaitlistCount,
+			&appointment.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning open appointment row: %w", err)
+		}
+
+		appointment.IsOpen = true
+		if capacity.Valid {
+			c := int(capacity.Int64)
+			appointment.Capacity = &c
+		}
+
+		if err := json.Unmarshal(hostJSON, &appointment.Host); err != nil {
+			return nil, fmt.Errorf("error unmarshaling host data: %w", err)
+		}
+
+		appointments = append(appointments, appointment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open appointment rows: %w", err)
+	}
+
+	return appointments, nil
+}
+
+// appointmentTags keeps the column NOT NULL for appointments without tags.
+func appointmentTags(appointment *models.Appointment) []string {
+	if appointment.Tags == nil {
+		return []string{}
+	}
+	return appointment.Tags
+}
+
+// SearchAppointments finds the appointments the user hosts or is invited to
+// matching every set field of the filter. With a query they are ordered by
+// relevance, otherwise the latest come first. The status of an appointment is
+// derived from its required attendance like models.DeriveAppointmentStatus.
+func (r *appointmentRepository) SearchAppointments(ctx context.Context, userId int, filter models.AppointmentSearch) ([]models.AppointmentSearchResult, error) {
+	args := []interface{}{userId}
+	conditions := []string{`(
+				a.host_id = $1
+				OR EXISTS (SELECT 1 FROM invitations i WHERE i.appointment_id = a.appointment_id AND i.invitee_id = $1)
+			)`}
+
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	rank := "0"
+	if filter.Query != "" {
+		addCondition("a.search_vector @@ websearch_to_tsquery('english', $%d)", filter.Query)
+		rank = fmt.Sprintf("ts_rank(a.search_vector, websearch_to_tsquery('english', $%d))", len(args))
+	}
+	if filter.HostId != nil {
+		addCondition("a.host_id = $%d", *filter.HostId)
+	}
+	if filter.ParticipantId != nil {
+		addCondition(`(
+				a.host_id = $%[1]d
+				OR EXISTS (SELECT 1 FROM invitations i WHERE i.appointment_id = a.appointment_id AND i.invitee_id = $%[1]d)
+			)`, *filter.ParticipantId)
+	}
+	if !filter.From.IsZero() {
+		addCondition("a.end_time > $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("a.start_time < $%d", filter.To)
+	}
+	if len(filter.Tags) > 0 {
+		addCondition("a.tags @> $%d", pq.Array(filter.Tags))
+	}
+	if filter.ResourceId != nil {
+		addCondition(`EXISTS (
+				SELECT 1 FROM appointment_resources ar WHERE ar.appointment_id = a.appointment_id AND ar.resource_id = $%d
+			)`, *filter.ResourceId)
+	}
+
+	statusCondition := fmt.Sprintf("status <> '%s'", models.AppointmentStatusCancelled)
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		statusCondition = fmt.Sprintf("status = $%d", len(args))
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s, status, rank
+		FROM (
+			SELECT a.*,
+				CASE
+					WHEN a.cancelled_at IS NOT NULL THEN '%s'
+					WHEN att.accepted >= COALESCE(a.quorum, att.total) THEN '%s'
+					WHEN att.accepted + att.pending < COALESCE(a.quorum, att.total) THEN '%s'
+					ELSE '%s'
+				END AS status,
+				%s AS rank
+			FROM appointments a
+			CROSS JOIN LATERAL (
+				SELECT
+					COUNT(*) AS total,
+					COUNT(*) FILTER (WHERE inv.status = 'accepted') AS accepted,
+					COUNT(*) FILTER (WHERE inv.status = 'pending') AS pending
+				FROM invitations inv
+				WHERE inv.appointment_id = a.appointment_id
+					AND inv.role = 'required'
+			) att
+			WHERE %s
+		) matches
+		WHERE %s
+		ORDER BY rank DESC, start_time DESC, appointment_id DESC
+		LIMIT $%d OFFSET $%d;
+	`, appointmentColumns, models.AppointmentStatusCancelled, models.AppointmentStatusConfirmed, models.AppointmentStatusAtRisk,
+		models.AppointmentStatusTentative, rank, strings.Join(conditions, " AND "), statusCondition, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching appointments: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.AppointmentSearchResult{}
+
+	for rows.Next() {
+		var result models.AppointmentSearchResult
+
+		appointment, err := scanAppointment(rows, &result.AppointmentStatus, &result.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning appointment row: %w", err)
+		}
+		result.Appointment = *appointment
+
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating appointment rows: %w", err)
+	}
+
+	return results, nil
+}
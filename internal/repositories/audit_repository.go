@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+)
+
+type AuditRepository interface {
+	InsertAuditEvent(ctx context.Context, tx *sql.Tx, event *models.AuditEvent) error
+
+	GetAuditEventsByAppointment(ctx context.Context, appointmentId int) ([]models.AuditEvent, error)
+	SearchAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// InsertAuditEvent writes the event in the transaction of the change it
+// describes, so a change is never committed without its audit trail.
+func (r *auditRepository) InsertAuditEvent(ctx context.Context, tx *sql.Tx, event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events
+			(appointment_id, entity_type, entity_id, action, actor_id, request_id, before, after, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING audit_event_id;
+	`
+
+	return tx.QueryRowContext(ctx,
+		query, event.AppointmentId, event.EntityType, event.EntityId, event.Action, event.ActorId,
+		event.RequestId, []byte(event.Before), []byte(event.After), event.CreatedAt,
+	).Scan(&event.AuditEventId)
+}
+
+const auditEventColumns = `audit_event_id, appointment_id, entity_type, entity_id, action, actor_id, request_id,
+		before, after, created_at`
+
+func (r *auditRepository) GetAuditEventsByAppointment(ctx context.Context, appointmentId int) ([]models.AuditEvent, error) {
+	query := `
+		SELECT ` + auditEventColumns + `
+		FROM audit_events
+		WHERE appointment_id = $1
+		ORDER BY created_at, audit_event_id;
+	`
+
+	return r.queryAuditEvents(ctx, query, appointmentId)
+}
+
+func (r *auditRepository) SearchAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.AppointmentId != nil {
+		addCondition("appointment_id = $%d", *filter.AppointmentId)
+	}
+	if filter.ActorId != nil {
+		addCondition("actor_id = $%d", *filter.ActorId)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM audit_events
+		%s
+		ORDER BY created_at DESC, audit_event_id DESC
+		LIMIT $%d OFFSET $%d;
+	`, auditEventColumns, where, len(args)-1, len(args))
+
+	return r.queryAuditEvents(ctx, query, args...)
+}
+
+func (r *auditRepository) queryAuditEvents(ctx context.Context, query string, args ...interface{}) ([]models.AuditEvent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+
+	for rows.Next() {
+		var event models.AuditEvent
+		var actorId sql.NullInt64
+		var before, after []byte
+
+		err := rows.Scan(
+			&event.AuditEventId, &event.AppointmentId, &event.EntityType, &event.EntityId, &event.Action,
+			&actorId, &event.RequestId, &before, &after, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning audit event row: %w", err)
+		}
+
+		if actorId.Valid {
+			id := int(actorId.Int64)
+			event.ActorId = &id
+		}
+		event.Before = before
+		event.After = after
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit event rows: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrBookingPageNotFound = apperror.NotFound("booking page not found")
+	ErrSlugTaken           = apperror.Conflict("booking page slug already taken")
+)
+
+type BookingRepository interface {
+	BeginBookingTx(ctx context.Context) (*sql.Tx, error)
+	InsertBookingPage(ctx context.Context, tx *sql.Tx, page *models.BookingPage) (*models.BookingPage, error)
+	InsertEventType(ctx context.Context, tx *sql.Tx, eventType *models.EventType) (*models.EventType, error)
+	InsertGuest(ctx context.Context, tx *sql.Tx, guest *models.Guest) (*models.Guest, error)
+
+	GetBookingPagesByHost(ctx context.Context, hostId int) ([]models.BookingPage, error)
+	GetBookingPageBySlug(ctx context.Context, slug string) (*models.BookingPage, error)
+	GetEventTypeBookings(ctx context.Context, eventTypeId int, startDate, endDate time.Time) ([]models.TimeSlot, error)
+}
+
+type bookingRepository struct {
+	db *sql.DB
+}
+
+func NewBookingRepository(db *sql.DB) BookingRepository {
+	return &bookingRepository{db: db}
+}
+
+func (r *bookingRepository) BeginBookingTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *bookingRepository) InsertBookingPage(ctx context.Context, tx *sql.Tx, page *models.BookingPage) (*models.BookingPage, error) {
+	query := `
+		INSERT INTO booking_pages
+			(host_id, slug, title, description, is_active, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING booking_page_id;
+	`
+
+	err := tx.QueryRowContext(ctx,
+		query, page.HostId, page.Slug, page.Title, page.Description, page.IsActive, page.CreatedAt,
+	).Scan(&page.BookingPageId)
+
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrSlugTaken
+		}
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (r *bookingRepository) InsertEventType(ctx context.Context, tx *sql.Tx, eventType *models.EventType) (*models.EventType, error) {
+	query := `
+		INSERT INTO event_types
+			(booking_page_id, title, duration_minutes, buffer_before_minutes, buffer_after_minutes,
+			min_notice_minutes, max_per_day, availability, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING event_type_id;
+	`
+
+	availability, err := json.Marshal(eventType.Availability)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling availability: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx,
+		query, eventType.BookingPageId, eventType.Title, eventType.DurationMinutes, eventType.BufferBeforeMinutes,
+		eventType.BufferAfterMinutes, eventType.MinNoticeMinutes, eventType.MaxPerDay, availability, eventType.CreatedAt,
+	).Scan(&eventType.EventTypeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return eventType, nil
+}
+
+func (r *bookingRepository) InsertGuest(ctx context.Context, tx *sql.Tx, guest *models.Guest) (*models.Guest, error) {
+	query := `
+		INSERT INTO appointment_guests
+			(appointment_id, name, email, notes, created_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		RETURNING guest_id;
+	`
+
+	err := tx.QueryRowContext(ctx,
+		query, guest.AppointmentId, guest.Name, guest.Email, guest.Notes, guest.CreatedAt,
+	).Scan(&guest.GuestId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return guest, nil
+}
+
+const bookingPageQuery = `
+	SELECT
+		bp.booking_page_id,
+		bp.host_id,
+		bp.slug,
+		bp.title,
+		bp.description,
+		bp.is_active,
+		bp.created_at,
+		jsonb_build_object(
+			'user_id', host.user_id,
+			'username', host.username,
+			'name', host.name,
+			'timezone', host.timezone
+		) AS host,
+		COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'event_type_id', et.event_type_id,
+				'booking_page_id', et.booking_page_id,
+				'title', et.title,
+				'duration_minutes', et.duration_minutes,
+				'buffer_before_minutes', et.buffer_before_minutes,
+				'buffer_after_minutes', et.buffer_after_minutes,
+				'min_notice_minutes', et.min_notice_minutes,
+				'max_per_day', et.max_per_day,
+				'availability', et.availability,
+				'created_at', et.created_at
+			) ORDER BY et.event_type_id)
+			FROM event_types et
+			WHERE et.booking_page_id = bp.booking_page_id
+		), '[]'::jsonb) AS event_types
+	FROM booking_pages bp
+	JOIN users host ON bp.host_id = host.user_id
+`
+
+func scanBookingPage(scan func(dest ...interface{}) error) (*models.BookingPage, error) {
+	var page models.BookingPage
+	var hostJSON, eventTypesJSON []byte
+
+	err := scan(
+		&page.BookingPageId, &page.HostId, &page.Slug, &page.Title, &page.Description,
+		&page.IsActive, &page.CreatedAt, &hostJSON, &eventTypesJSON,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(hostJSON, &page.Host); err != nil {
+		return nil, fmt.Errorf("error unmarshaling host data: %w", err)
+	}
+
+	if err := json.Unmarshal(eventTypesJSON, &page.EventTypes); err != nil {
+		return nil, fmt.Errorf("error unmarshaling event types data: %w", err)
+	}
+
+	return &page, nil
+}
+
+func (r *bookingRepository) GetBookingPagesByHost(ctx context.Context, hostId int) ([]models.BookingPage, error) {
+	query := bookingPageQuery + `
+		WHERE bp.host_id = $1
+		ORDER BY bp.created_at;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, hostId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying booking pages: %w", err)
+	}
+	defer rows.Close()
+
+	var pages []models.BookingPage
+
+	for rows.Next() {
+		page, err := scanBookingPage(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning booking page row: %w", err)
+		}
+		pages = append(pages, *page)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating booking page rows: %w", err)
+	}
+
+	return pages, nil
+}
+
+func (r *bookingRepository) GetBookingPageBySlug(ctx context.Context, slug string) (*models.BookingPage, error) {
+	query := bookingPageQuery + `
+		WHERE bp.slug = $1 AND bp.is_active AND host.deleted_at IS NULL;
+	`
+
+	page, err := scanBookingPage(r.db.QueryRowContext(ctx, query, slug).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookingPageNotFound
+		}
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (r *bookingRepository) GetEventTypeBookings(ctx context.Context, eventTypeId int, startDate, endDate time.Time) ([]models.TimeSlot, error) {
+	query := `
+		SELECT a.start_time, a.end_time
+		FROM appointments a
+		WHERE a.event_type_id = $1
+			AND a.cancelled_at IS NULL
+			AND a.start_time < $3
+			AND a.end_time > $2
+		ORDER BY a.start_time;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, eventTypeId, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error querying event type bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var slots []models.TimeSlot
+
+	for rows.Next() {
+		var slot models.TimeSlot
+		if err := rows.Scan(&slot.StartTime, &slot.EndTime); err != nil {
+			return nil, fmt.Errorf("error scanning event type booking row: %w", err)
+		}
+		slots = append(slots, slot)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating event type booking rows: %w", err)
+	}
+
+	return slots, nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+)
+
+// cachedAppointmentRepository serves GetAppointmentsByUserId from the list
+// cache, per user and window. Everything else goes to the wrapped repository.
+type cachedAppointmentRepository struct {
+	AppointmentRepository
+	cache ListCache
+}
+
+func NewCachedAppointmentRepository(appointmentRepository AppointmentRepository, cache ListCache) AppointmentRepository {
+	return &cachedAppointmentRepository{
+		AppointmentRepository: appointmentRepository,
+		cache:                 cache,
+	}
+}
+
+func (r *cachedAppointmentRepository) GetAppointmentsByUserId(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.AppointmentInvitation, error) {
+	var appointments []models.AppointmentInvitation
+
+	list := fmt.Sprintf("appointments:%d:%d", startDate.Unix(), endDate.Unix())
+	key, hit := r.cache.Load(ctx, userId, list, &appointments)
+	if hit {
+		return appointments, nil
+	}
+
+	appointments, err := r.AppointmentRepository.GetAppointmentsByUserId(ctx, userId, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.Store(ctx, key, appointments)
+	return appointments, nil
+}
+
+// cachedInvitationRepository serves GetInvitations from the list cache.
+type cachedInvitationRepository struct {
+	InvitationRepository
+	cache ListCache
+}
+
+func NewCachedInvitationRepository(invitationRepository InvitationRepository, cache ListCache) InvitationRepository {
+	return &cachedInvitationRepository{
+		InvitationRepository: invitationRepository,
+		cache:                cache,
+	}
+}
+
+func (r *cachedInvitationRepository) GetInvitations(ctx context.Context, userId int) ([]models.AppointmentInvitation, error) {
+	var invitations []models.AppointmentInvitation
+
+	key, hit := r.cache.Load(ctx, userId, "invitations", &invitations)
+	if hit {
+		return invitations, nil
+	}
+
+	invitations, err := r.InvitationRepository.GetInvitations(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.Store(ctx, key, invitations)
+	return invitations, nil
+}
@@ -0,0 +1,256 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrHolidayCalendarNotFound = apperror.NotFound("holiday calendar not found")
+	ErrHolidayCalendarUser     = apperror.NotFound("user is not assigned to the holiday calendar")
+)
+
+type HolidayRepository interface {
+	BeginHolidayTx(ctx context.Context) (*sql.Tx, error)
+	InsertHolidayCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error)
+	GetHolidayCalendars(ctx context.Context) ([]models.HolidayCalendar, error)
+	GetHolidayCalendarById(ctx context.Context, calendarId int) (*models.HolidayCalendar, error)
+
+	UpsertHoliday(ctx context.Context, tx *sql.Tx, holiday *models.Holiday) error
+	GetHolidays(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.Holiday, error)
+
+	AssignHolidayCalendarUsers(ctx context.Context, calendarId int, userIds []int) error
+	UnassignHolidayCalendarUser(ctx context.Context, calendarId int, userId int) error
+	GetHolidayCalendarUsers(ctx context.Context, calendarId int) ([]models.User, error)
+}
+
+type holidayRepository struct {
+	db *sql.DB
+}
+
+func NewHolidayRepository(db *sql.DB) HolidayRepository {
+	return &holidayRepository{db: db}
+}
+
+func (r *holidayRepository) BeginHolidayTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *holidayRepository) InsertHolidayCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error) {
+	query := `
+		INSERT INTO holiday_calendars
+			(name, created_at)
+		VALUES
+			($1, $2)
+		RETURNING holiday_calendar_id;
+	`
+
+	err := r.db.QueryRowContext(ctx, query, calendar.Name, calendar.CreatedAt).Scan(&calendar.HolidayCalendarId)
+	if err != nil {
+		return nil, err
+	}
+
+	return calendar, nil
+}
+
+func (r *holidayRepository) GetHolidayCalendars(ctx context.Context) ([]models.HolidayCalendar, error) {
+	query := `
+		SELECT holiday_calendar_id, name, created_at
+		FROM holiday_calendars
+		ORDER BY name;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying holiday calendars: %w", err)
+	}
+	defer rows.Close()
+
+	var calendars []models.HolidayCalendar
+
+	for rows.Next() {
+		var calendar models.HolidayCalendar
+		if err := rows.Scan(&calendar.HolidayCalendarId, &calendar.Name, &calendar.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning holiday calendar row: %w", err)
+		}
+		calendars = append(calendars, calendar)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating holiday calendar rows: %w", err)
+	}
+
+	return calendars, nil
+}
+
+func (r *holidayRepository) GetHolidayCalendarById(ctx context.Context, calendarId int) (*models.HolidayCalendar, error) {
+	query := `
+		SELECT holiday_calendar_id, name, created_at
+		FROM holiday_calendars
+		WHERE holiday_calendar_id = $1;
+	`
+
+	var calendar models.HolidayCalendar
+
+	err := r.db.QueryRowContext(ctx, query, calendarId).Scan(&calendar.HolidayCalendarId, &calendar.Name, &calendar.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrHolidayCalendarNotFound
+		}
+		return nil, err
+	}
+
+	return &calendar, nil
+}
+
+// UpsertHoliday inserts the holiday or refreshes it when the calendar already
+// holds an entry with the same ICS UID.
+func (r *holidayRepository) UpsertHoliday(ctx context.Context, tx *sql.Tx, holiday *models.Holiday) error {
+	query := `
+		INSERT INTO holidays
+			(holiday_calendar_id, uid, name, start_date, end_date, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (holiday_calendar_id, uid) DO UPDATE
+		SET
+			name = EXCLUDED.name,
+			start_date = EXCLUDED.start_date,
+			end_date = EXCLUDED.end_date
+		RETURNING holiday_id;
+	`
+
+	return tx.QueryRowContext(ctx,
+		query, holiday.HolidayCalendarId, holiday.Uid, holiday.Name, holiday.StartDate, holiday.EndDate,
+		holiday.CreatedAt,
+	).Scan(&holiday.HolidayId)
+}
+
+// GetHolidays returns the holidays of the calendars the user is assigned to.
+func (r *holidayRepository) GetHolidays(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.Holiday, error) {
+	query := `
+		SELECT h.holiday_id, h.holiday_calendar_id, h.uid, h.name, h.start_date, h.end_date, h.created_at
+		FROM holidays h
+		JOIN holiday_calendar_users hcu ON hcu.holiday_calendar_id = h.holiday_calendar_id
+		WHERE hcu.user_id = $1
+			AND h.start_date < $3::date AND h.end_date > $2::date
+		ORDER BY h.start_date;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userId, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error querying holidays: %w", err)
+	}
+	defer rows.Close()
+
+	var holidays []models.Holiday
+
+	for rows.Next() {
+		var holiday models.Holiday
+
+		err := rows.Scan(
+			&holiday.HolidayId, &holiday.HolidayCalendarId, &holiday.Uid, &holiday.Name,
+			&holiday.StartDate, &holiday.EndDate, &holiday.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning holiday row: %w", err)
+		}
+
+		holidays = append(holidays, holiday)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating holiday rows: %w", err)
+	}
+
+	return holidays, nil
+}
+
+// AssignHolidayCalendarUsers adds the users to the calendar, users already
+// assigned are kept. ErrUserNotFound when one of them does not exist.
+func (r *holidayRepository) AssignHolidayCalendarUsers(ctx context.Context, calendarId int, userIds []int) error {
+	query := `
+		WITH matched AS (
+			SELECT u.user_id
+			FROM users u
+			WHERE u.user_id = ANY($2) AND u.deleted_at IS NULL
+		), assigned AS (
+			INSERT INTO holiday_calendar_users (holiday_calendar_id, user_id, created_at)
+			SELECT $1, m.user_id, $3
+			FROM matched m
+			ON CONFLICT (holiday_calendar_id, user_id) DO NOTHING
+		)
+		SELECT COUNT(*) FROM matched;
+	`
+
+	ids := make([]int64, 0, len(userIds))
+	for _, id := range userIds {
+		ids = append(ids, int64(id))
+	}
+
+	var matched int
+	err := r.db.QueryRowContext(ctx, query, calendarId, pq.Array(ids), time.Now().UTC()).Scan(&matched)
+	if err != nil {
+		return err
+	}
+	if matched != len(ids) {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *holidayRepository) UnassignHolidayCalendarUser(ctx context.Context, calendarId int, userId int) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM holiday_calendar_users WHERE holiday_calendar_id = $1 AND user_id = $2;`, calendarId, userId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrHolidayCalendarUser
+	}
+
+	return nil
+}
+
+func (r *holidayRepository) GetHolidayCalendarUsers(ctx context.Context, calendarId int) ([]models.User, error) {
+	query := `
+		SELECT u.user_id, u.name, u.username, u.role, u.timezone
+		FROM holiday_calendar_users hcu
+		JOIN users u ON u.user_id = hcu.user_id
+		WHERE hcu.holiday_calendar_id = $1 AND u.deleted_at IS NULL
+		ORDER BY u.name;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, calendarId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying holiday calendar users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.UserId, &user.Name, &user.Username, &user.Role, &user.Timezone); err != nil {
+			return nil, fmt.Errorf("error scanning holiday calendar user row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating holiday calendar user rows: %w", err)
+	}
+
+	return users, nil
+}
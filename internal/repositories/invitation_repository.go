@@ -1,19 +1,37 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/lib/pq"
 )
 
+var (
+	ErrInvitationNotFound = apperror.NotFound("invitation not found")
+	ErrInvitationModified = apperror.PreconditionFailed("invitation was changed since it was read")
+)
+
 type InvitationRepository interface {
-	InsertInvitation(tx *sql.Tx, invitations []models.Invitation) error
-	GetInvitations(userId int) ([]models.AppointmentInvitation, error)
-	UpdateStatusInvitation(userId int, invId int, status string) error
+	BeginInvitationTx(ctx context.Context) (*sql.Tx, error)
+	InsertInvitation(ctx context.Context, tx *sql.Tx, invitations []models.Invitation) error
+	GetInvitations(ctx context.Context, userId int) ([]models.AppointmentInvitation, error)
+	UpdateStatusInvitation(ctx context.Context, tx *sql.Tx, invitation *models.Invitation, status string) error
+
+	GetInvitationById(ctx context.Context, tx *sql.Tx, userId int, invId int) (*models.Invitation, error)
+	GetInvitationByInvitee(ctx context.Context, tx *sql.Tx, appointmentId int, inviteeId int) (*models.Invitation, error)
+	CountInvitationsByStatus(ctx context.Context, tx *sql.Tx, appointmentId int, status string) (int, error)
+	GetFirstWaitlisted(ctx context.Context, tx *sql.Tx, appointmentId int) (*models.Invitation, error)
+	GetInvitationsByAppointment(ctx context.Context, tx *sql.Tx, appointmentId int) ([]models.Invitation, error)
+	DeleteInvitation(ctx context.Context, tx *sql.Tx, invId int) error
+
+	GetAttendees(ctx context.Context, appointmentId int, filter models.AttendeeFilter) ([]models.Attendee, error)
+	CountAttendees(ctx context.Context, appointmentId int) (*models.AttendeeCounts, error)
 }
 
 type invitationRepository struct {
@@ -24,13 +42,18 @@ func NewInvitationRepository(db *sql.DB) InvitationRepository {
 	return &invitationRepository{db: db}
 }
 
-func (r *invitationRepository) InsertInvitation(tx *sql.Tx, invitations []models.Invitation) error {
+func (r *invitationRepository) BeginInvitationTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *invitationRepository) InsertInvitation(ctx context.Context, tx *sql.Tx, invitations []models.Invitation) error {
 	if len(invitations) == 0 {
 		return nil
 	}
 
 	var appointmentIDs []int64
 	var inviteeIDs []int64
+	var roles []string
 	var statuses []string
 	var notes []string
 	var createdAts []time.Time
@@ -38,35 +61,43 @@ func (r *invitationRepository) InsertInvitation(tx *sql.Tx, invitations []models
 	for _, inv := range invitations {
 		appointmentIDs = append(appointmentIDs, int64(inv.AppointmentId))
 		inviteeIDs = append(inviteeIDs, int64(inv.InviteeId))
+		roles = append(roles, inv.Role)
 		statuses = append(statuses, inv.Status)
-		notes = append(notes, "")
+		notes = append(notes, inv.Notes)
 		createdAts = append(createdAts, time.Now())
 	}
 
 	query := `
-		INSERT INTO stg_appointment.invitations 
-			(appointment_id, invitee_id, status, notes, created_at)
-		SELECT * FROM UNNEST($1::bigint[], $2::bigint[], $3::text[], $4::text[], $5::timestamptz[])
+		INSERT INTO invitations 
+			(appointment_id, invitee_id, role, status, notes, created_at)
+		SELECT * FROM UNNEST($1::bigint[], $2::bigint[], $3::text[], $4::text[], $5::text[], $6::timestamptz[])
 	`
 
-	_, err := tx.Exec(query, pq.Array(appointmentIDs), pq.Array(inviteeIDs), pq.Array(statuses), pq.Array(notes), pq.Array(createdAts))
+	_, err := tx.ExecContext(ctx, query, pq.Array(appointmentIDs), pq.Array(inviteeIDs), pq.Array(roles), pq.Array(statuses), pq.Array(notes), pq.Array(createdAts))
 	return err
 }
 
-func (r *invitationRepository) GetInvitations(userId int) ([]models.AppointmentInvitation, error) {
+func (r *invitationRepository) GetInvitations(ctx context.Context, userId int) ([]models.AppointmentInvitation, error) {
 	query := `
 		WITH user_tz AS (
 			SELECT timezone
-			FROM stg_appointment.users
+			FROM users
 			WHERE user_id = $1
 		),
 		appointment_details AS (
 			SELECT 
 				a.appointment_id,
 				a.title,
+				a.description,
+				a.location,
+				a.travel_minutes,
+				a.meeting_provider,
+				a.join_url,
+				a.quorum,
 				timezone((SELECT timezone FROM user_tz), a.start_time) AS start_time,
 				timezone((SELECT timezone FROM user_tz), a.end_time) AS end_time,
 				a.created_at AS appointment_created_at,
+				a.version,
 				a.host_id,
 				-- Host information
 				jsonb_build_object(
@@ -77,9 +108,21 @@ func (r *invitationRepository) GetInvitations(userId int) ([]models.AppointmentI
 				-- Get total attendants count
 				(
 					SELECT COUNT(*)
-					FROM stg_appointment.invitations inv
+					FROM invitations inv
 					WHERE inv.appointment_id = a.appointment_id
 				) AS total_attendants,
+				-- Required attendance summary for the derived appointment status
+				(
+					SELECT jsonb_build_object(
+						'total', COUNT(*),
+						'accepted', COUNT(*) FILTER (WHERE inv.status = 'accepted'),
+						'rejected', COUNT(*) FILTER (WHERE inv.status = 'rejected'),
+						'pending', COUNT(*) FILTER (WHERE inv.status = 'pending')
+					)
+					FROM invitations inv
+					WHERE inv.appointment_id = a.appointment_id
+						AND inv.role = 'required'
+				) AS required_attendance,
 				-- Limited attendants list (only 3)
 				(
 					SELECT jsonb_agg(attendant_info)
@@ -89,44 +132,56 @@ func (r *invitationRepository) GetInvitations(userId int) ([]models.AppointmentI
 							'name', u.name,
 							'timezone', u.timezone,
 							'status', inv.status,
+							'role', inv.role,
 							'invitation_id', inv.invitation_id,
 							'invitee_id', inv.invitee_id
 						) as attendant_info
-						FROM stg_appointment.invitations inv
-						JOIN stg_appointment.users u ON inv.invitee_id = u.user_id
+						FROM invitations inv
+						JOIN users u ON inv.invitee_id = u.user_id
 						WHERE inv.appointment_id = a.appointment_id
 						LIMIT 3
 					) limited_attendants
 				) AS limited_attendants
-			FROM stg_appointment.appointments a
-			JOIN stg_appointment.users host ON a.host_id = host.user_id
-			JOIN stg_appointment.invitations i ON a.appointment_id = i.appointment_id
-			WHERE i.invitee_id = $1     
-				AND a.host_id != $1        
-				AND i.status = 'pending' 
+			FROM appointments a
+			JOIN users host ON a.host_id = host.user_id
+			JOIN invitations i ON a.appointment_id = i.appointment_id
+			WHERE i.invitee_id = $1
+				AND a.host_id != $1
+				AND i.status = 'pending'
+				AND a.cancelled_at IS NULL
 		)
 		SELECT 
 			ad.appointment_id,
 			ad.title,
+			ad.description,
+			ad.location,
+			ad.travel_minutes,
+			ad.meeting_provider,
+			ad.join_url,
+			ad.quorum,
 			ad.start_time,
 			ad.end_time,
 			ad.appointment_created_at,
+			ad.version,
 			ad.host,
 			ad.total_attendants,
+			ad.required_attendance,
 			COALESCE(ad.limited_attendants, '[]'::jsonb) as attendants,
 			-- Invitation details for the current user
 			i.invitation_id,
+			i.version AS invitation_version,
 			i.invitee_id,
+			i.role,
 			i.status,
 			i.created_at AS invitation_created_at
 		FROM appointment_details ad
-		JOIN stg_appointment.invitations i ON 
+		JOIN invitations i ON 
 			ad.appointment_id = i.appointment_id 
 			AND i.invitee_id = $1
 		ORDER BY ad.start_time;
 	`
 
-	rows, err := r.db.Query(query, userId)
+	rows, err := r.db.QueryContext(ctx, query, userId)
 	if err != nil {
 		return nil, fmt.Errorf("error querying appointments: %w", err)
 	}
@@ -136,20 +191,30 @@ func (r *invitationRepository) GetInvitations(userId int) ([]models.AppointmentI
 
 	for rows.Next() {
 		var appointment models.AppointmentInvitation
-		var hostJSON, attendantsJSON []byte
-		var invitationID sql.NullInt64
+		var hostJSON, requiredJSON, attendantsJSON []byte
+		var invitationID, quorum sql.NullInt64
 
 		err := rows.Scan(
 			&appointment.AppointmentId,
 			&appointment.Title,
+			&appointment.Description,
+			&appointment.Location,
+			&appointment.TravelMinutes,
+			&appointment.MeetingProvider,
+			&appointment.JoinUrl,
+			&quorum,
 			&appointment.StartTime,
 			&appointment.EndTime,
 			&appointment.CreatedAt,
+			&appointment.Version,
 			&hostJSON,
 			&appointment.TotalAttendants,
+			&requiredJSON,
 			&attendantsJSON,
 			&invitationID,
+			&appointment.InvitationVersion,
 			&appointment.Invitee_id,
+			&appointment.Role,
 			&appointment.Status,
 			&appointment.CreatedAt,
 		)
@@ -169,6 +234,17 @@ func (r *invitationRepository) GetInvitations(userId int) ([]models.AppointmentI
 			return nil, fmt.Errorf("error unmarshaling attendants data: %w", err)
 		}
 
+		if err := json.Unmarshal(requiredJSON, &appointment.RequiredAttendance); err != nil {
+			return nil, fmt.Errorf("error unmarshaling required attendance data: %w", err)
+		}
+
+		if quorum.Valid {
+			q := int(quorum.Int64)
+			appointment.Quorum = &q
+		}
+
+		appointment.AppointmentStatus = models.DeriveAppointmentStatus(appointment.Quorum, appointment.RequiredAttendance)
+
 		appointments = append(appointments, appointment)
 	}
 
@@ -179,15 +255,209 @@ func (r *invitationRepository) GetInvitations(userId int) ([]models.AppointmentI
 	return appointments, nil
 }
 
-func (r *invitationRepository) UpdateStatusInvitation(userId int, invId int, status string) error {
+// UpdateStatusInvitation sets the status if the invitation is still at
+// invitation.Version, so a status nobody has seen is never overwritten, and
+// bumps the version. Otherwise it returns ErrInvitationModified.
+func (r *invitationRepository) UpdateStatusInvitation(ctx context.Context, tx *sql.Tx, invitation *models.Invitation, status string) error {
 	query := `
-		UPDATE  stg_appointment.invitations
+		UPDATE  invitations
 		SET 
-			status = $1
+			status = $1,
+			version = version + 1
 		WHERE 
-			invitee_id = $2 AND invitation_id = $3;
+			invitee_id = $2 AND invitation_id = $3 AND version = $4
+		RETURNING version;
 	`
 
-	_, err := r.db.Exec(query, status, userId, invId)
-	return err
+	err := tx.QueryRowContext(ctx, query, status, invitation.InviteeId, invitation.InvitationId, invitation.Version).Scan(&invitation.Version)
+	if err == sql.ErrNoRows {
+		return ErrInvitationModified
+	}
+	if err != nil {
+		return err
+	}
+
+	invitation.Status = status
+	return nil
+}
+
+const invitationColumns = `invitation_id, appointment_id, invitee_id, role, status, COALESCE(notes, ''), created_at, version`
+
+func scanInvitation(row *sql.Row) (*models.Invitation, error) {
+	var invitation models.Invitation
+
+	err := row.Scan(
+		&invitation.InvitationId, &invitation.AppointmentId, &invitation.InviteeId, &invitation.Role,
+		&invitation.Status, &invitation.Notes, &invitation.CreatedAt, &invitation.Version,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+func (r *invitationRepository) GetInvitationById(ctx context.Context, tx *sql.Tx, userId int, invId int) (*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+		WHERE invitee_id = $1 AND invitation_id = $2
+		FOR UPDATE;
+	`
+
+	return scanInvitation(tx.QueryRowContext(ctx, query, userId, invId))
+}
+
+func (r *invitationRepository) GetInvitationByInvitee(ctx context.Context, tx *sql.Tx, appointmentId int, inviteeId int) (*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+		WHERE appointment_id = $1 AND invitee_id = $2;
+	`
+
+	return scanInvitation(tx.QueryRowContext(ctx, query, appointmentId, inviteeId))
+}
+
+func (r *invitationRepository) CountInvitationsByStatus(ctx context.Context, tx *sql.Tx, appointmentId int, status string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM invitations
+		WHERE appointment_id = $1 AND status = $2;
+	`
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, appointmentId, status).Scan(&count)
+	return count, err
+}
+
+// GetFirstWaitlisted returns the longest waiting invitation of the appointment.
+// Callers must hold the appointment lock, see AppointmentRepository.LockAppointment.
+func (r *invitationRepository) GetFirstWaitlisted(ctx context.Context, tx *sql.Tx, appointmentId int) (*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+		WHERE appointment_id = $1 AND status = 'waitlisted'
+		ORDER BY created_at, invitation_id
+		LIMIT 1;
+	`
+
+	return scanInvitation(tx.QueryRowContext(ctx, query, appointmentId))
+}
+
+func (r *invitationRepository) GetInvitationsByAppointment(ctx context.Context, tx *sql.Tx, appointmentId int) ([]models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+		WHERE appointment_id = $1
+		ORDER BY invitation_id;
+	`
+
+	rows, err := tx.QueryContext(ctx, query, appointmentId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying appointment invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []models.Invitation
+
+	for rows.Next() {
+		var invitation models.Invitation
+		err := rows.Scan(
+			&invitation.InvitationId, &invitation.AppointmentId, &invitation.InviteeId, &invitation.Role,
+			&invitation.Status, &invitation.Notes, &invitation.CreatedAt, &invitation.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invitation row: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invitation rows: %w", err)
+	}
+
+	return invitations, nil
+}
+
+func (r *invitationRepository) DeleteInvitation(ctx context.Context, tx *sql.Tx, invId int) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM invitations WHERE invitation_id = $1;`, invId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+// GetAttendees pages through the invitations of an appointment in the order
+// they were sent, optionally only those with filter.Status.
+func (r *invitationRepository) GetAttendees(ctx context.Context, appointmentId int, filter models.AttendeeFilter) ([]models.Attendee, error) {
+	query := `
+		SELECT inv.invitation_id, inv.invitee_id, u.username, u.name, u.timezone, inv.role, inv.status, inv.created_at
+		FROM invitations inv
+		JOIN users u ON inv.invitee_id = u.user_id
+		WHERE inv.appointment_id = $1
+			AND ($2::text = '' OR inv.status = $2)
+		ORDER BY inv.created_at, inv.invitation_id
+		LIMIT $3 OFFSET $4;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, appointmentId, filter.Status, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attendees: %w", err)
+	}
+	defer rows.Close()
+
+	attendees := []models.Attendee{}
+
+	for rows.Next() {
+		var attendee models.Attendee
+		err := rows.Scan(
+			&attendee.InvitationId, &attendee.InviteeId, &attendee.Username, &attendee.Name, &attendee.Timezone,
+			&attendee.Role, &attendee.Status, &attendee.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attendee row: %w", err)
+		}
+		attendees = append(attendees, attendee)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attendee rows: %w", err)
+	}
+
+	return attendees, nil
+}
+
+func (r *invitationRepository) CountAttendees(ctx context.Context, appointmentId int) (*models.AttendeeCounts, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'accepted'),
+			COUNT(*) FILTER (WHERE status = 'pending'),
+			COUNT(*) FILTER (WHERE status = 'rejected'),
+			COUNT(*) FILTER (WHERE status = 'waitlisted')
+		FROM invitations
+		WHERE appointment_id = $1;
+	`
+
+	var counts models.AttendeeCounts
+	err := r.db.QueryRowContext(ctx, query, appointmentId).Scan(
+		&counts.Total, &counts.Accepted, &counts.Pending, &counts.Rejected, &counts.Waitlisted,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error counting attendees: %w", err)
+	}
+
+	return &counts, nil
 }
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrDuplicateJob = errors.New("job with the same unique key is already queued")
+
+const (
+	jobDataKey      = "jobs:data"      // hash, job id -> job JSON
+	jobScheduledKey = "jobs:scheduled" // zset, job id -> unix ms the job is due
+	jobInflightKey  = "jobs:inflight"  // zset, job id -> unix ms its visibility timeout ends
+	jobDeadKey      = "jobs:dead"      // list of job ids that ran out of attempts
+	jobUniquePrefix = "jobs:unique:"
+)
+
+type JobRepository interface {
+	Enqueue(ctx context.Context, job *models.Job, runAt time.Time) error
+	Claim(ctx context.Context, visibility time.Duration) (*models.Job, error)
+	Ack(ctx context.Context, job *models.Job) error
+	Retry(ctx context.Context, job *models.Job, runAt time.Time) error
+	Bury(ctx context.Context, job *models.Job) error
+
+	OldestDue(ctx context.Context) (*time.Time, error)
+}
+
+type jobRepository struct {
+	client *redis.Client
+}
+
+func NewJobRepository(client *redis.Client) JobRepository {
+	return &jobRepository{client: client}
+}
+
+// enqueueScript stores the job unless its unique key is taken.
+var enqueueScript = redis.NewScript(`
+	if ARGV[4] ~= '' and redis.call('SET', ARGV[4], ARGV[1], 'NX') == false then
+		return 0
+	end
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+	return 1
+`)
+
+// claimScript first hands jobs whose visibility timeout ended back to the
+// queue, then moves the oldest due job in flight.
+var claimScript = redis.NewScript(`
+	local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+	for _, id in ipairs(expired) do
+		redis.call('ZREM', KEYS[2], id)
+		redis.call('ZADD', KEYS[1], ARGV[1], id)
+	end
+
+	local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+	if #due == 0 then
+		return false
+	end
+
+	redis.call('ZREM', KEYS[1], due[1])
+	redis.call('ZADD', KEYS[2], ARGV[2], due[1])
+	return redis.call('HGET', KEYS[3], due[1])
+`)
+
+// finishScript removes a job from flight and either deletes it, schedules it
+// again or moves it to the dead-letter list. The unique key is released
+// unless the job is scheduled again.
+var finishScript = redis.NewScript(`
+	if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+		return 0
+	end
+
+	if ARGV[2] == 'ack' then
+		redis.call('HDEL', KEYS[2], ARGV[1])
+	elseif ARGV[2] == 'retry' then
+		redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+		redis.call('ZADD', KEYS[3], ARGV[4], ARGV[1])
+		return 1
+	else
+		redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+		redis.call('LPUSH', KEYS[4], ARGV[1])
+	end
+
+	if ARGV[5] ~= '' and redis.call('GET', ARGV[5]) == ARGV[1] then
+		redis.call('DEL', ARGV[5])
+	end
+	return 1
+`)
+
+func (r *jobRepository) Enqueue(ctx context.Context, job *models.Job, runAt time.Time) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("error marshalling job: %w", err)
+	}
+
+	added, err := enqueueScript.Run(ctx, r.client,
+		[]string{jobDataKey, jobScheduledKey},
+		job.JobId, data, runAt.UnixMilli(), uniqueKey(job),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("error enqueue job: %w", err)
+	}
+	if added == 0 {
+		return ErrDuplicateJob
+	}
+
+	return nil
+}
+
+// Claim returns the next due job or nil when there is none. The job is hidden
+// from other workers until visibility passes, after that it is due again.
+func (r *jobRepository) Claim(ctx context.Context, visibility time.Duration) (*models.Job, error) {
+	now := time.Now()
+
+	data, err := claimScript.Run(ctx, r.client,
+		[]string{jobScheduledKey, jobInflightKey, jobDataKey},
+		now.UnixMilli(), now.Add(visibility).UnixMilli(),
+	).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error claim job: %w", err)
+	}
+
+	var job models.Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("error unmarshalling job: %w", err)
+	}
+
+	return &job, nil
+}
+
+func (r *jobRepository) Ack(ctx context.Context, job *models.Job) error {
+	return r.finish(ctx, job, "ack", time.Time{})
+}
+
+func (r *jobRepository) Retry(ctx context.Context, job *models.Job, runAt time.Time) error {
+	return r.finish(ctx, job, "retry", runAt)
+}
+
+func (r *jobRepository) Bury(ctx context.Context, job *models.Job) error {
+	return r.finish(ctx, job, "bury", time.Time{})
+}
+
+func (r *jobRepository) finish(ctx context.Context, job *models.Job, outcome string, runAt time.Time) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("error marshalling job: %w", err)
+	}
+
+	err = finishScript.Run(ctx, r.client,
+		[]string{jobInflightKey, jobDataKey, jobScheduledKey, jobDeadKey},
+		job.JobId, outcome, data, runAt.UnixMilli(), uniqueKey(job),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("error %s job: %w", outcome, err)
+	}
+
+	return nil
+}
+
+func uniqueKey(job *models.Job) string {
+	if job.UniqueKey == "" {
+		return ""
+	}
+	return jobUniquePrefix + job.UniqueKey
+}
+
+// OldestDue returns when the longest waiting job that is due became due, nil
+// when no job is waiting to be claimed.
+func (r *jobRepository) OldestDue(ctx context.Context) (*time.Time, error) {
+	due, err := r.client.ZRangeByScoreWithScores(ctx, jobScheduledKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprint(time.Now().UnixMilli()),
+		Count: 1,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	dueAt := time.UnixMilli(int64(due[0].Score))
+	return &dueAt, nil
+}
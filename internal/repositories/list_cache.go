@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/go-redis/redis/v8"
+)
+
+// listGenerationTTL keeps the generation of a user well beyond the lifetime
+// of the entries filed under it, see ListCache.Invalidate.
+const listGenerationTTL = 24 * time.Hour
+
+// ListCache keeps per-user list query results in Redis. Entries are filed
+// under a generation of the user, so invalidating a user is one write and
+// makes all of their entries unreachable at once, whatever the window.
+type ListCache interface {
+	// Load fills value with the cached list and tells whether it was there.
+	// The returned key is where to Store a freshly queried list; it is taken
+	// before the query so a list read across an invalidation is never served.
+	Load(ctx context.Context, userId int, list string, value interface{}) (key string, hit bool)
+	Store(ctx context.Context, key string, value interface{})
+	// Invalidate drops the cached lists of the users. Call it once the
+	// change is committed, otherwise a concurrent read may cache the old rows.
+	Invalidate(ctx context.Context, userIds ...int)
+}
+
+type listCache struct {
+	redisRepository RedisRepository
+	ttl             time.Duration
+}
+
+// NewListCache returns a cache keeping entries for ttl, a ttl of 0 turns it
+// off.
+func NewListCache(redisRepository RedisRepository, ttl time.Duration) ListCache {
+	return &listCache{
+		redisRepository: redisRepository,
+		ttl:             ttl,
+	}
+}
+
+func listGenerationKey(userId int) string {
+	return "cache:generation:" + strconv.Itoa(userId)
+}
+
+func (c *listCache) Load(ctx context.Context, userId int, list string, value interface{}) (string, bool) {
+	if c.ttl <= 0 {
+		return "", false
+	}
+
+	generation, err := c.redisRepository.Get(ctx, listGenerationKey(userId))
+	if errors.Is(err, redis.Nil) {
+		generation, err = "0", nil
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "list cache unavailable", "error", err.Error())
+		return "", false
+	}
+
+	key := fmt.Sprintf("cache:%s:%d:%s", list, userId, generation)
+
+	cached, err := c.redisRepository.Get(ctx, key)
+	if err == nil && json.Unmarshal([]byte(cached), value) == nil {
+		metrics.ListCacheRequests.WithLabelValues(listName(list), "hit").Inc()
+		return key, true
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		slog.WarnContext(ctx, "list cache unavailable", "error", err.Error())
+		return "", false
+	}
+
+	metrics.ListCacheRequests.WithLabelValues(listName(list), "miss").Inc()
+	return key, false
+}
+
+func (c *listCache) Store(ctx context.Context, key string, value interface{}) {
+	if key == "" {
+		return
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		slog.WarnContext(ctx, "list cache encode", "error", err.Error())
+		return
+	}
+
+	if err := c.redisRepository.Set(ctx, key, encoded, c.ttl); err != nil {
+		slog.WarnContext(ctx, "list cache unavailable", "error", err.Error())
+	}
+}
+
+// Invalidate moves the users to a fresh random generation. Generations never
+// repeat, so an entry of an old one cannot come back when the generation
+// key itself expires.
+func (c *listCache) Invalidate(ctx context.Context, userIds ...int) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	for _, userId := range userIds {
+		generation := make([]byte, 8)
+		if _, err := rand.Read(generation); err != nil {
+			slog.ErrorContext(ctx, "list cache invalidate", "user_id", userId, "error", err.Error())
+			continue
+		}
+
+		err := c.redisRepository.Set(ctx, listGenerationKey(userId), hex.EncodeToString(generation), listGenerationTTL)
+		if err != nil {
+			// the lists of the user stay stale until their entries expire
+			slog.ErrorContext(ctx, "list cache invalidate", "user_id", userId, "error", err.Error())
+		}
+	}
+}
+
+// listName is the list without its window, e.g. "appointments".
+func listName(list string) string {
+	for i := range list {
+		if list[i] == ':' {
+			return list[:i]
+		}
+	}
+	return list
+}
@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// countingInvitationRepository answers GetInvitations with a fixed list and
+// counts how often it was asked.
+type countingInvitationRepository struct {
+	InvitationRepository
+	invitations []models.AppointmentInvitation
+	calls       int
+}
+
+func (r *countingInvitationRepository) GetInvitations(ctx context.Context, userId int) ([]models.AppointmentInvitation, error) {
+	r.calls++
+	return r.invitations, nil
+}
+
+func newTestListCache(t *testing.T, ttl time.Duration) (ListCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewListCache(NewRedisRepository(client), ttl), mr
+}
+
+func cacheRequests(list, result string) float64 {
+	return testutil.ToFloat64(metrics.ListCacheRequests.WithLabelValues(list, result))
+}
+
+func TestListCacheMissThenHit(t *testing.T) {
+	cache, _ := newTestListCache(t, time.Minute)
+	ctx := context.Background()
+
+	var value []string
+	key, hit := cache.Load(ctx, 1, "invitations", &value)
+	if hit {
+		t.Fatal("first load hit an empty cache")
+	}
+	if key == "" {
+		t.Fatal("miss returned no key to store under")
+	}
+
+	cache.Store(ctx, key, []string{"a", "b"})
+
+	_, hit = cache.Load(ctx, 1, "invitations", &value)
+	if !hit {
+		t.Fatal("load after store missed")
+	}
+	if len(value) != 2 || value[0] != "a" || value[1] != "b" {
+		t.Fatalf("loaded %v, want [a b]", value)
+	}
+}
+
+func TestListCacheEntriesExpire(t *testing.T) {
+	cache, mr := newTestListCache(t, time.Minute)
+	ctx := context.Background()
+
+	var value []string
+	key, _ := cache.Load(ctx, 1, "invitations", &value)
+	cache.Store(ctx, key, []string{"a"})
+
+	mr.FastForward(2 * time.Minute)
+
+	if _, hit := cache.Load(ctx, 1, "invitations", &value); hit {
+		t.Fatal("entry outlived its ttl")
+	}
+}
+
+func TestListCacheInvalidateIsPerUser(t *testing.T) {
+	cache, _ := newTestListCache(t, time.Minute)
+	ctx := context.Background()
+
+	var value []string
+	for _, userId := range []int{1, 2} {
+		key, _ := cache.Load(ctx, userId, "invitations", &value)
+		cache.Store(ctx, key, []string{"a"})
+	}
+
+	cache.Invalidate(ctx, 1)
+
+	if _, hit := cache.Load(ctx, 1, "invitations", &value); hit {
+		t.Error("invalidated user still hits")
+	}
+	if _, hit := cache.Load(ctx, 2, "invitations", &value); !hit {
+		t.Error("other user lost their entry")
+	}
+}
+
+func TestListCacheStoreAfterInvalidateIsNotServed(t *testing.T) {
+	cache, _ := newTestListCache(t, time.Minute)
+	ctx := context.Background()
+
+	var value []string
+	key, _ := cache.Load(ctx, 1, "invitations", &value)
+
+	// the list was read before a change committed and is stored after it
+	cache.Invalidate(ctx, 1)
+	cache.Store(ctx, key, []string{"stale"})
+
+	if _, hit := cache.Load(ctx, 1, "invitations", &value); hit {
+		t.Fatal("list read before the invalidation was served")
+	}
+}
+
+func TestListCacheZeroTTLDisablesCache(t *testing.T) {
+	cache, mr := newTestListCache(t, 0)
+	ctx := context.Background()
+
+	var value []string
+	key, hit := cache.Load(ctx, 1, "invitations", &value)
+	if hit || key != "" {
+		t.Fatalf("disabled cache loaded key %q, hit %v", key, hit)
+	}
+
+	cache.Store(ctx, key, []string{"a"})
+	cache.Invalidate(ctx, 1)
+
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Fatalf("disabled cache wrote %v", keys)
+	}
+}
+
+func TestListCacheCountsHitsAndMisses(t *testing.T) {
+	cache, _ := newTestListCache(t, time.Minute)
+	ctx := context.Background()
+
+	hits, misses := cacheRequests("appointments", "hit"), cacheRequests("appointments", "miss")
+
+	var value []string
+	key, _ := cache.Load(ctx, 1, "appointments:0:86400", &value)
+	cache.Store(ctx, key, []string{"a"})
+	cache.Load(ctx, 1, "appointments:0:86400", &value)
+	cache.Load(ctx, 1, "appointments:0:86400", &value)
+
+	if got := cacheRequests("appointments", "miss") - misses; got != 1 {
+		t.Errorf("misses went up by %v, want 1", got)
+	}
+	if got := cacheRequests("appointments", "hit") - hits; got != 2 {
+		t.Errorf("hits went up by %v, want 2", got)
+	}
+}
+
+func TestCachedInvitationRepositoryQueriesOnMissOnly(t *testing.T) {
+	cache, _ := newTestListCache(t, time.Minute)
+	ctx := context.Background()
+
+	inner := &countingInvitationRepository{
+		invitations: []models.AppointmentInvitation{{Appointment: models.Appointment{AppointmentId: 7, Title: "Standup"}}},
+	}
+	repo := NewCachedInvitationRepository(inner, cache)
+
+	for i := 0; i < 2; i++ {
+		invitations, err := repo.GetInvitations(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(invitations) != 1 || invitations[0].AppointmentId != 7 {
+			t.Fatalf("got %+v, want the appointment 7 invitation", invitations)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("queried %d times, want 1", inner.calls)
+	}
+
+	cache.Invalidate(ctx, 1)
+
+	if _, err := repo.GetInvitations(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 2 {
+		t.Fatalf("queried %d times after invalidation, want 2", inner.calls)
+	}
+}
@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/lib/pq"
+)
+
+type OutboxRepository interface {
+	InsertEvent(ctx context.Context, tx *sql.Tx, event *models.OutboxEvent) error
+
+	BeginOutboxTx(ctx context.Context) (*sql.Tx, error)
+	ClaimEvents(ctx context.Context, tx *sql.Tx, handler string, limit int) ([]models.OutboxEvent, error)
+	MarkProcessed(ctx context.Context, tx *sql.Tx, eventId int64, handler string) error
+	RecordFailure(ctx context.Context, tx *sql.Tx, eventId int64, handler string, reason string, maxAttempts int) (deadLettered bool, err error)
+
+	PruneProcessed(ctx context.Context, before time.Time, handlers []string) (int64, error)
+	OldestPending(ctx context.Context, handlers []string) (*time.Time, error)
+}
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// InsertEvent writes the event in the transaction of the change that caused
+// it, so the event exists if and only if the change was committed.
+func (r *outboxRepository) InsertEvent(ctx context.Context, tx *sql.Tx, event *models.OutboxEvent) error {
+	query := `
+		INSERT INTO outbox_events
+			(event_type, appointment_id, recipients, payload, created_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		RETURNING outbox_event_id;
+	`
+
+	recipients := make([]int64, 0, len(event.Recipients))
+	for _, id := range event.Recipients {
+		recipients = append(recipients, int64(id))
+	}
+
+	return tx.QueryRowContext(ctx,
+		query, event.EventType, event.AppointmentId, pq.Array(recipients), []byte(event.Payload), event.CreatedAt,
+	).Scan(&event.OutboxEventId)
+}
+
+func (r *outboxRepository) BeginOutboxTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+// outboxUndone matches the event of the alias when handler $1 has neither
+// processed nor dead-lettered it.
+func outboxUndone(alias string) string {
+	return `NOT EXISTS (
+		SELECT 1
+		FROM outbox_processed p
+		WHERE p.outbox_event_id = ` + alias + `.outbox_event_id AND p.handler = $1
+	)
+	AND NOT EXISTS (
+		SELECT 1
+		FROM outbox_failures f
+		WHERE f.outbox_event_id = ` + alias + `.outbox_event_id AND f.handler = $1 AND f.dead_lettered_at IS NOT NULL
+	)`
+}
+
+// ClaimEvents locks the oldest events the handler is not done with and returns
+// them in order. Events locked by another relay are skipped, and so are the
+// events of an appointment behind one that is skipped or waits for the retry
+// of a failure: events of one appointment reach the handler in order, the
+// other appointments move on. The locks are held until tx ends.
+func (r *outboxRepository) ClaimEvents(ctx context.Context, tx *sql.Tx, handler string, limit int) ([]models.OutboxEvent, error) {
+	query := `
+		SELECT o.outbox_event_id, o.event_type, o.appointment_id, o.recipients, o.payload, o.created_at,
+			(
+				SELECT COUNT(*)
+				FROM outbox_events b
+				WHERE b.appointment_id = o.appointment_id AND b.outbox_event_id < o.outbox_event_id
+					AND ` + outboxUndone("b") + `
+			) AS undone_before
+		FROM outbox_events o
+		WHERE ` + outboxUndone("o") + `
+			AND NOT EXISTS (
+				SELECT 1
+				FROM outbox_events b
+				JOIN outbox_failures f ON f.outbox_event_id = b.outbox_event_id
+				WHERE b.appointment_id = o.appointment_id AND b.outbox_event_id <= o.outbox_event_id
+					AND f.handler = $1 AND f.dead_lettered_at IS NULL AND f.retry_at > NOW()
+			)
+		ORDER BY o.outbox_event_id
+		LIMIT $2
+		FOR UPDATE OF o SKIP LOCKED;
+	`
+
+	rows, err := tx.QueryContext(ctx, query, handler, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	// claimed events per appointment, an event whose earlier undone events
+	// were not all claimed waits for them
+	claimed := map[int]int{}
+
+	for rows.Next() {
+		var event models.OutboxEvent
+		var recipients pq.Int64Array
+		var payload []byte
+		var undoneBefore int
+
+		err := rows.Scan(&event.OutboxEventId, &event.EventType, &event.AppointmentId, &recipients, &payload, &event.CreatedAt, &undoneBefore)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning outbox event row: %w", err)
+		}
+
+		if claimed[event.AppointmentId] != undoneBefore {
+			continue
+		}
+		claimed[event.AppointmentId]++
+
+		for _, id := range recipients {
+			event.Recipients = append(event.Recipients, int(id))
+		}
+		event.Payload = payload
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox event rows: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *outboxRepository) MarkProcessed(ctx context.Context, tx *sql.Tx, eventId int64, handler string) error {
+	query := `
+		INSERT INTO outbox_processed (outbox_event_id, handler)
+		VALUES ($1, $2);
+	`
+
+	_, err := tx.ExecContext(ctx, query, eventId, handler)
+	return err
+}
+
+// RecordFailure counts a failed attempt of the handler at the event and
+// schedules the retry with exponential backoff. On the maxAttempts-th failure
+// the event is dead-lettered instead and the handler moves past it.
+func (r *outboxRepository) RecordFailure(ctx context.Context, tx *sql.Tx, eventId int64, handler string, reason string, maxAttempts int) (bool, error) {
+	query := `
+		INSERT INTO outbox_failures AS f
+			(outbox_event_id, handler, attempts, last_error, retry_at, dead_lettered_at)
+		VALUES
+			($1, $2, 1, $3, NOW() + INTERVAL '2 seconds', CASE WHEN $4 <= 1 THEN NOW() END)
+		ON CONFLICT (outbox_event_id, handler) DO UPDATE
+		SET
+			attempts = f.attempts + 1,
+			last_error = EXCLUDED.last_error,
+			retry_at = NOW() + LEAST(POWER(2, f.attempts + 1), 3600) * INTERVAL '1 second',
+			dead_lettered_at = CASE WHEN f.attempts + 1 >= $4 THEN NOW() END
+		RETURNING f.dead_lettered_at IS NOT NULL;
+	`
+
+	var deadLettered bool
+	err := tx.QueryRowContext(ctx, query, eventId, handler, reason, maxAttempts).Scan(&deadLettered)
+	if err != nil {
+		return false, fmt.Errorf("error recording outbox failure: %w", err)
+	}
+
+	return deadLettered, nil
+}
+
+// outboxDoneCount counts the handlers in the array parameter handlersParam
+// that are done with event e, those that processed it and those that
+// dead-lettered it.
+func outboxDoneCount(handlersParam string) string {
+	return `(
+		SELECT COUNT(*)
+		FROM outbox_processed p
+		WHERE p.outbox_event_id = e.outbox_event_id AND p.handler = ANY(` + handlersParam + `)
+	) + (
+		SELECT COUNT(*)
+		FROM outbox_failures f
+		WHERE f.outbox_event_id = e.outbox_event_id AND f.handler = ANY(` + handlersParam + `)
+			AND f.dead_lettered_at IS NOT NULL
+	)`
+}
+
+// PruneProcessed deletes events created before the given time that every one
+// of the handlers is done with, dead letters included.
+func (r *outboxRepository) PruneProcessed(ctx context.Context, before time.Time, handlers []string) (int64, error) {
+	query := `
+		DELETE FROM outbox_events e
+		WHERE e.created_at < $1
+			AND ` + outboxDoneCount("$2") + ` = $3;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before, pq.Array(handlers), len(handlers))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// OldestPending returns when the oldest event that some of the handlers are
+// not done with yet was created, nil when they are all caught up.
+func (r *outboxRepository) OldestPending(ctx context.Context, handlers []string) (*time.Time, error) {
+	query := `
+		SELECT MIN(e.created_at)
+		FROM outbox_events e
+		WHERE ` + outboxDoneCount("$1") + ` < $2;
+	`
+
+	var oldest sql.NullTime
+	if err := r.db.QueryRowContext(ctx, query, pq.Array(handlers), len(handlers)).Scan(&oldest); err != nil {
+		return nil, err
+	}
+
+	if !oldest.Valid {
+		return nil, nil
+	}
+
+	return &oldest.Time, nil
+}
@@ -2,15 +2,29 @@ package repositories
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"time"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/go-redis/redis/v8"
 )
 
 type RedisRepository interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
 	Get(ctx context.Context, key string) (string, error)
 	Delete(ctx context.Context, key string) error
+	DeleteByPattern(ctx context.Context, pattern string) error
+
+	Publish(ctx context.Context, channel string, message interface{}) error
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+
+	Ping(ctx context.Context) error
+
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	SlidingWindow(ctx context.Context, key string, policy models.RateLimitPolicy) (*models.RateLimit, error)
 }
 
 type redisRepository struct {
@@ -27,6 +41,11 @@ func (r *redisRepository) Set(ctx context.Context, key string, value interface{}
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
+// SetNX sets key only when it does not exist yet and tells whether it did.
+func (r *redisRepository) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (r *redisRepository) Get(ctx context.Context, key string) (string, error) {
 	return r.client.Get(ctx, key).Result()
 }
@@ -34,3 +53,110 @@ func (r *redisRepository) Get(ctx context.Context, key string) (string, error) {
 func (r *redisRepository) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
+
+// DeleteByPattern removes every key matching the glob pattern. Keys are found
+// with SCAN so large keyspaces do not block the server.
+func (r *redisRepository) DeleteByPattern(ctx context.Context, pattern string) error {
+	iter := r.client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+func (r *redisRepository) Publish(ctx context.Context, channel string, message interface{}) error {
+	return r.client.Publish(ctx, channel, message).Err()
+}
+
+func (r *redisRepository) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return r.client.Subscribe(ctx, channels...)
+}
+
+func (r *redisRepository) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Incr increments the counter at key. A new counter expires after expiration,
+// later increments keep the original expiry.
+func (r *redisRepository) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	n, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if n == 1 {
+		if err := r.client.Expire(ctx, key, expiration).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// TTL returns the time to live of key, zero or less when the key is missing
+// or never expires.
+func (r *redisRepository) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return r.client.TTL(ctx, key).Result()
+}
+
+// slidingWindowScript keeps the request times of the window in a sorted set.
+// It drops the times that left the window and records the request only when
+// the limit is not reached. Times come from the Redis clock so replicas with
+// skewed clocks share one window.
+//
+// KEYS[1] window key, ARGV[1] window in ms, ARGV[2] limit, ARGV[3] unique member.
+// Returns allowed (0/1), requests in the window, ms until the oldest leaves.
+var slidingWindowScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, count, reset}
+`)
+
+// SlidingWindow counts a request against key under policy, atomically.
+func (r *redisRepository) SlidingWindow(ctx context.Context, key string, policy models.RateLimitPolicy) (*models.RateLimit, error) {
+	member := make([]byte, 8)
+	if _, err := rand.Read(member); err != nil {
+		return nil, err
+	}
+
+	result, err := slidingWindowScript.Run(ctx, r.client, []string{key},
+		policy.Window.Milliseconds(), policy.Limit, hex.EncodeToString(member)).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := policy.Limit - int(result[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &models.RateLimit{
+		Allowed:   result[0] == 1,
+		Limit:     policy.Limit,
+		Remaining: remaining,
+		Reset:     time.Duration(result[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrResourceNotFound = apperror.NotFound("resource not found")
+	ErrResourceConflict = apperror.Conflict("resource already booked for the requested time")
+)
+
+type ResourceRepository interface {
+	GetResources(ctx context.Context) ([]models.Resource, error)
+	GetResourceById(ctx context.Context, resourceId int) (*models.Resource, error)
+	InsertResource(ctx context.Context, resource *models.Resource) (*models.Resource, error)
+
+	BookResources(ctx context.Context, tx *sql.Tx, appointmentId int, resourceIds []int, startTime, endTime time.Time) error
+	RescheduleResources(ctx context.Context, tx *sql.Tx, appointmentId int, startTime, endTime time.Time) error
+	ReleaseResources(ctx context.Context, tx *sql.Tx, appointmentId int) error
+	GetResourceBusy(ctx context.Context, resourceId int, startDate, endDate time.Time) ([]models.TimeSlot, error)
+	GetResourceBookings(ctx context.Context, resourceId int, startDate, endDate time.Time) ([]models.ResourceBooking, error)
+}
+
+type resourceRepository struct {
+	db *sql.DB
+}
+
+func NewResourceRepository(db *sql.DB) ResourceRepository {
+	return &resourceRepository{db: db}
+}
+
+func (r *resourceRepository) GetResources(ctx context.Context) ([]models.Resource, error) {
+	query := `
+		SELECT resource_id, name, type, capacity, location, timezone, created_at
+		FROM resources
+		WHERE deleted_at IS NULL
+		ORDER BY type, name;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying resources: %w", err)
+	}
+	defer rows.Close()
+
+	var resources []models.Resource
+
+	for rows.Next() {
+		var resource models.Resource
+		var capacity sql.NullInt64
+
+		err := rows.Scan(
+			&resource.ResourceId, &resource.Name, &resource.Type, &capacity,
+			&resource.Location, &resource.Timezone, &resource.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning resource row: %w", err)
+		}
+
+		if capacity.Valid {
+			c := int(capacity.Int64)
+			resource.Capacity = &c
+		}
+
+		resources = append(resources, resource)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating resource rows: %w", err)
+	}
+
+	return resources, nil
+}
+
+func (r *resourceRepository) GetResourceById(ctx context.Context, resourceId int) (*models.Resource, error) {
+	query := `
+		SELECT resource_id, name, type, capacity, location, timezone, created_at
+		FROM resources
+		WHERE resource_id = $1 AND deleted_at IS NULL;
+	`
+
+	var resource models.Resource
+	var capacity sql.NullInt64
+
+	err := r.db.QueryRowContext(ctx, query, resourceId).Scan(
+		&resource.ResourceId, &resource.Name, &resource.Type, &capacity,
+		&resource.Location, &resource.Timezone, &resource.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrResourceNotFound
+		}
+		return nil, err
+	}
+
+	if capacity.Valid {
+		c := int(capacity.Int64)
+		resource.Capacity = &c
+	}
+
+	return &resource, nil
+}
+
+func (r *resourceRepository) InsertResource(ctx context.Context, resource *models.Resource) (*models.Resource, error) {
+	query := `
+		INSERT INTO resources
+			(name, type, capacity, location, timezone, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING resource_id;
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query, resource.Name, resource.Type, resource.Capacity, resource.Location, resource.Timezone,
+		resource.CreatedAt,
+	).Scan(&resource.ResourceId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// BookResources reserves the resources for the appointment time range. Double
+// bookings are rejected by the exclusion constraint on appointment_resources,
+// so concurrent transactions cannot both succeed.
+func (r *resourceRepository) BookResources(ctx context.Context, tx *sql.Tx, appointmentId int, resourceIds []int, startTime, endTime time.Time) error {
+	if len(resourceIds) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(resourceIds))
+	for _, id := range resourceIds {
+		ids = append(ids, int64(id))
+	}
+
+	query := `
+		INSERT INTO appointment_resources
+			(appointment_id, resource_id, during)
+		SELECT $1, res.resource_id, tstzrange($3, $4, '[)')
+		FROM resources res
+		WHERE res.resource_id = ANY($2) AND res.deleted_at IS NULL;
+	`
+
+	result, err := tx.ExecContext(ctx, query, appointmentId, pq.Array(ids), startTime, endTime)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23P01" {
+			return ErrResourceConflict
+		}
+		return err
+	}
+
+	booked, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(booked) != len(ids) {
+		return ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// RescheduleResources moves the resource bookings of an appointment to its new
+// time range. The exclusion constraint rejects moves onto booked time.
+func (r *resourceRepository) RescheduleResources(ctx context.Context, tx *sql.Tx, appointmentId int, startTime, endTime time.Time) error {
+	query := `
+		UPDATE appointment_resources
+		SET during = tstzrange($2, $3, '[)')
+		WHERE appointment_id = $1;
+	`
+
+	_, err := tx.ExecContext(ctx, query, appointmentId, startTime, endTime)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23P01" {
+			return ErrResourceConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *resourceRepository) ReleaseResources(ctx context.Context, tx *sql.Tx, appointmentId int) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM appointment_resources WHERE appointment_id = $1;`, appointmentId)
+	return err
+}
+
+func (r *resourceRepository) GetResourceBusy(ctx context.Context, resourceId int, startDate, endDate time.Time) ([]models.TimeSlot, error) {
+	query := `
+		SELECT lower(ar.during), upper(ar.during)
+		FROM appointment_resources ar
+		WHERE ar.resource_id = $1
+			AND ar.during && tstzrange($2, $3, '[)')
+		ORDER BY lower(ar.during);
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, resourceId, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error querying resource busy: %w", err)
+	}
+	defer rows.Close()
+
+	var slots []models.TimeSlot
+
+	for rows.Next() {
+		var slot models.TimeSlot
+		if err := rows.Scan(&slot.StartTime, &slot.EndTime); err != nil {
+			return nil, fmt.Errorf("error scanning resource busy row: %w", err)
+		}
+		slots = append(slots, slot)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating resource busy rows: %w", err)
+	}
+
+	return slots, nil
+}
+
+func (r *resourceRepository) GetResourceBookings(ctx context.Context, resourceId int, startDate, endDate time.Time) ([]models.ResourceBooking, error) {
+	query := `
+		SELECT
+			a.appointment_id,
+			a.title,
+			lower(ar.during) AS start_time,
+			upper(ar.during) AS end_time,
+			jsonb_build_object(
+				'user_id', host.user_id,
+				'username', host.username,
+				'name', host.name,
+				'timezone', host.timezone
+			) AS host
+		FROM appointment_resources ar
+		JOIN appointments a ON ar.appointment_id = a.appointment_id
+		JOIN users host ON a.host_id = host.user_id
+		WHERE ar.resource_id = $1
+			AND ar.during && tstzrange($2, $3, '[)')
+		ORDER BY lower(ar.during);
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, resourceId, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error querying resource bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []models.ResourceBooking
+
+	for rows.Next() {
+		var booking models.ResourceBooking
+		var hostJSON []byte
+
+		err := rows.Scan(&booking.AppointmentId, &booking.Title, &booking.StartTime, &booking.EndTime, &hostJSON)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning resource booking row: %w", err)
+		}
+
+		if err := json.Unmarshal(hostJSON, &booking.Host); err != nil {
+			return nil, fmt.Errorf("error unmarshaling host data: %w", err)
+		}
+
+		bookings = append(bookings, booking)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating resource booking rows: %w", err)
+	}
+
+	return bookings, nil
+}
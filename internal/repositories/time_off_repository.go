@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+)
+
+var ErrTimeOffNotFound = apperror.NotFound("time off not found")
+
+type TimeOffRepository interface {
+	InsertTimeOff(ctx context.Context, timeOff *models.TimeOff) (*models.TimeOff, error)
+	GetTimeOffByUserId(ctx context.Context, userId int, from time.Time) ([]models.TimeOff, error)
+	DeleteTimeOff(ctx context.Context, userId int, timeOffId int) error
+}
+
+type timeOffRepository struct {
+	db *sql.DB
+}
+
+func NewTimeOffRepository(db *sql.DB) TimeOffRepository {
+	return &timeOffRepository{db: db}
+}
+
+func (r *timeOffRepository) InsertTimeOff(ctx context.Context, timeOff *models.TimeOff) (*models.TimeOff, error) {
+	query := `
+		INSERT INTO time_off
+			(user_id, start_time, end_time, reason, auto_reply, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING time_off_id;
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query, timeOff.UserId, timeOff.StartTime, timeOff.EndTime, timeOff.Reason, timeOff.AutoReply,
+		timeOff.CreatedAt,
+	).Scan(&timeOff.TimeOffId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return timeOff, nil
+}
+
+func (r *timeOffRepository) GetTimeOffByUserId(ctx context.Context, userId int, from time.Time) ([]models.TimeOff, error) {
+	query := `
+		SELECT time_off_id, user_id, start_time, end_time, reason, auto_reply, created_at
+		FROM time_off
+		WHERE user_id = $1 AND end_time > $2
+		ORDER BY start_time;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userId, from)
+	if err != nil {
+		return nil, fmt.Errorf("error querying time off: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TimeOff
+
+	for rows.Next() {
+		var entry models.TimeOff
+
+		err := rows.Scan(
+			&entry.TimeOffId, &entry.UserId, &entry.StartTime, &entry.EndTime, &entry.Reason,
+			&entry.AutoReply, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning time off row: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating time off rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *timeOffRepository) DeleteTimeOff(ctx context.Context, userId int, timeOffId int) error {
+	query := `
+		DELETE FROM time_off
+		WHERE user_id = $1 AND time_off_id = $2;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userId, timeOffId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrTimeOffNotFound
+	}
+
+	return nil
+}
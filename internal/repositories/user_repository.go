@@ -1,18 +1,26 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"time"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 )
 
+var ErrUserNotFound = apperror.NotFound("user not found")
+
 type UserRepository interface {
-	GetUsers() ([]models.User, error)
-	GetUserById(userId int) (*models.User, error)
-	GetUserByUsername(username string) (*models.User, error)
-	UpdateUserTimezone(userId int, timezone string) error
+	GetUsers(ctx context.Context) ([]models.User, error)
+	GetUserById(ctx context.Context, userId int) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	UpdateUserTimezone(ctx context.Context, userId int, timezone string) error
+	UpdateUserBuffers(ctx context.Context, userId int, bufferBefore int, bufferAfter int) error
+
+	InsertUser(ctx context.Context, user *models.User, passwordHash string) (*models.User, error)
+	UpdatePasswordHash(ctx context.Context, username string, passwordHash string) error
+	DisableUser(ctx context.Context, username string) (int, error)
 }
 
 type userRepository struct {
@@ -23,26 +31,27 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) GetUserByUsername(username string) (*models.User, error) {
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `
 		SELECT
 			u.user_id, u.name, u.username, u.role, u.timezone, timezone(u.timezone, u.created_at) as created_at,
-			timezone(u.timezone, u.updated_at) as updated_at
-		FROM stg_appointment.users u
-			WHERE u.username = $1 AND u.deleted_at IS NULL
+			timezone(u.timezone, u.updated_at) as updated_at, u.buffer_before_minutes, u.buffer_after_minutes,
+			COALESCE(u.password_hash, '')
+		FROM users u
+			WHERE u.username = $1 AND u.deleted_at IS NULL AND u.disabled_at IS NULL
 		LIMIT 1;
 	`
 
 	var user models.User
 	var updated, deleted sql.NullString
 
-	err := r.db.QueryRow(query, username).Scan(
+	err := r.db.QueryRowContext(ctx, query, username).Scan(
 		&user.UserId, &user.Name, &user.Username, &user.Role, &user.Timezone,
-		&user.CreatedAt, &updated,
+		&user.CreatedAt, &updated, &user.BufferBeforeMinutes, &user.BufferAfterMinutes, &user.PasswordHash,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -67,24 +76,25 @@ func (r *userRepository) GetUserByUsername(username string) (*models.User, error
 	return &user, nil
 }
 
-func (r *userRepository) GetUserById(userId int) (*models.User, error) {
+func (r *userRepository) GetUserById(ctx context.Context, userId int) (*models.User, error) {
 	query := `
 		SELECT
 			u.user_id, u.name, u.username, u.timezone, timezone(u.timezone, u.created_at) as created_at,
-			timezone(u.timezone, u.updated_at) as updated_at
-		FROM stg_appointment.users u WHERE u.user_id = $1 AND u.deleted_at IS NULL
+			timezone(u.timezone, u.updated_at) as updated_at, u.buffer_before_minutes, u.buffer_after_minutes
+		FROM users u WHERE u.user_id = $1 AND u.deleted_at IS NULL AND u.disabled_at IS NULL
 		LIMIT 1;
 	`
 
 	var user models.User
 	var updated sql.NullString
 
-	err := r.db.QueryRow(query, userId).Scan(
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(
 		&user.UserId, &user.Name, &user.Username, &user.Timezone, &user.CreatedAt, &updated,
+		&user.BufferBeforeMinutes, &user.BufferAfterMinutes,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -101,17 +111,17 @@ func (r *userRepository) GetUserById(userId int) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) GetUsers() ([]models.User, error) {
+func (r *userRepository) GetUsers(ctx context.Context) ([]models.User, error) {
 	query := `
 		SELECT
 			u.user_id, u.name, u.username, u.timezone, 
 			timezone(u.timezone, u.created_at) as created_at,
 			timezone(u.timezone, u.updated_at) as updated_at
-		FROM stg_appointment.users u 
+		FROM users u 
 		WHERE u.deleted_at IS NULL;
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -140,14 +150,93 @@ func (r *userRepository) GetUsers() ([]models.User, error) {
 	return users, nil
 }
 
-func (r *userRepository) UpdateUserTimezone(userId int, timezone string) error {
+func (r *userRepository) UpdateUserTimezone(ctx context.Context, userId int, timezone string) error {
 	query := `
-		UPDATE stg_appointment.users
+		UPDATE users
 		SET
 			timezone = $1
 		WHERE user_id = $2;
 	`
 
-	_, err := r.db.Exec(query, timezone, userId)
+	_, err := r.db.ExecContext(ctx, query, timezone, userId)
+	return err
+}
+
+func (r *userRepository) UpdateUserBuffers(ctx context.Context, userId int, bufferBefore int, bufferAfter int) error {
+	query := `
+		UPDATE users
+		SET
+			buffer_before_minutes = $1,
+			buffer_after_minutes = $2,
+			updated_at = NOW()
+		WHERE user_id = $3;
+	`
+
+	_, err := r.db.ExecContext(ctx, query, bufferBefore, bufferAfter, userId)
 	return err
 }
+
+func (r *userRepository) InsertUser(ctx context.Context, user *models.User, passwordHash string) (*models.User, error) {
+	query := `
+		INSERT INTO users
+			(name, username, role, timezone, password_hash, created_at)
+		VALUES
+			($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING user_id;
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query, user.Name, user.Username, user.Role, user.Timezone, passwordHash, user.CreatedAt,
+	).Scan(&user.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, username string, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET
+			password_hash = $1,
+			updated_at = NOW()
+		WHERE username = $2 AND deleted_at IS NULL;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, passwordHash, username)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// DisableUser blocks the user from signing in and returns their id.
+// Disabling an already disabled user keeps the original time.
+func (r *userRepository) DisableUser(ctx context.Context, username string) (int, error) {
+	query := `
+		UPDATE users
+		SET
+			disabled_at = COALESCE(disabled_at, NOW()),
+			updated_at = NOW()
+		WHERE username = $1 AND deleted_at IS NULL
+		RETURNING user_id;
+	`
+
+	var userId int
+	err := r.db.QueryRowContext(ctx, query, username).Scan(&userId)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+
+	return userId, err
+}
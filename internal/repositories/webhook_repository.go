@@ -0,0 +1,338 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrWebhookEndpointNotFound = apperror.NotFound("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = apperror.NotFound("webhook delivery not found")
+)
+
+type WebhookRepository interface {
+	InsertEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) (*models.WebhookEndpoint, error)
+	GetEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	DeactivateEndpoint(ctx context.Context, endpointId int) error
+
+	EnqueueDeliveries(ctx context.Context, tx *sql.Tx, eventType string, payload []byte) error
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, deliveryId int64, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error
+
+	GetDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]models.WebhookDelivery, error)
+	GetDeliveryById(ctx context.Context, deliveryId int64) (*models.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, deliveryId int64) error
+}
+
+type webhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) InsertEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	query := `
+		INSERT INTO webhook_endpoints
+			(url, secret, event_types, is_active, created_by, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING webhook_endpoint_id;
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query, endpoint.Url, endpoint.Secret, pq.Array(endpoint.EventTypes), endpoint.IsActive, endpoint.CreatedBy,
+		endpoint.CreatedAt,
+	).Scan(&endpoint.WebhookEndpointId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+func (r *webhookRepository) GetEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	query := `
+		SELECT webhook_endpoint_id, url, event_types, is_active, COALESCE(created_by, 0), created_at
+		FROM webhook_endpoints
+		ORDER BY webhook_endpoint_id;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []models.WebhookEndpoint
+
+	for rows.Next() {
+		var endpoint models.WebhookEndpoint
+		err := rows.Scan(
+			&endpoint.WebhookEndpointId, &endpoint.Url, pq.Array(&endpoint.EventTypes), &endpoint.IsActive,
+			&endpoint.CreatedBy, &endpoint.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook endpoint row: %w", err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook endpoint rows: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func (r *webhookRepository) DeactivateEndpoint(ctx context.Context, endpointId int) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET is_active = FALSE
+		WHERE webhook_endpoint_id = $1;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, endpointId)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrWebhookEndpointNotFound
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries creates a delivery for every active endpoint subscribed to
+// the event type, in the transaction of the change that caused the event.
+func (r *webhookRepository) EnqueueDeliveries(ctx context.Context, tx *sql.Tx, eventType string, payload []byte) error {
+	query := `
+		INSERT INTO webhook_deliveries
+			(webhook_endpoint_id, event_type, payload)
+		SELECT e.webhook_endpoint_id, $1, $2
+		FROM webhook_endpoints e
+		WHERE e.is_active AND $1 = ANY(e.event_types);
+	`
+
+	_, err := tx.ExecContext(ctx, query, eventType, payload)
+	return err
+}
+
+// ClaimDueDeliveries picks deliveries that are due and pushes their next
+// attempt out by lease, so other dispatchers skip them while they are sent.
+// A dispatcher that dies mid-send leaves them to be retried after the lease.
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT webhook_delivery_id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhook_endpoints e
+		WHERE d.webhook_delivery_id = due.webhook_delivery_id
+			AND e.webhook_endpoint_id = d.webhook_endpoint_id
+		RETURNING d.webhook_delivery_id, d.webhook_endpoint_id, d.event_type, d.payload, d.attempts, e.url, e.secret;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var payload []byte
+		err := rows.Scan(
+			&delivery.WebhookDeliveryId, &delivery.WebhookEndpointId, &delivery.EventType, &payload,
+			&delivery.Attempts, &delivery.Url, &delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery row: %w", err)
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery rows: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) RecordAttempt(ctx context.Context, deliveryId int64, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	query := `
+		WITH attempt AS (
+			INSERT INTO webhook_delivery_attempts
+				(webhook_delivery_id, response_code, error, duration_ms, attempted_at)
+			VALUES
+				($1, $2, $3, $4, $5)
+		)
+		UPDATE webhook_deliveries
+		SET
+			attempts = attempts + 1,
+			status = $6,
+			last_response_code = $2,
+			last_error = $3,
+			next_attempt_at = $7,
+			delivered_at = CASE WHEN $6 = 'succeeded' THEN $5 ELSE delivered_at END
+		WHERE webhook_delivery_id = $1;
+	`
+
+	_, err := r.db.ExecContext(ctx,
+		query, deliveryId, attempt.ResponseCode, attempt.Error, attempt.DurationMs, attempt.AttemptedAt, status, nextAttemptAt,
+	)
+	return err
+}
+
+const webhookDeliveryColumns = `webhook_delivery_id, webhook_endpoint_id, event_type, payload, status, attempts,
+		next_attempt_at, last_response_code, last_error, delivered_at, created_at`
+
+func scanWebhookDelivery(scan func(dest ...interface{}) error) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload []byte
+	var responseCode sql.NullInt64
+
+	err := scan(
+		&delivery.WebhookDeliveryId, &delivery.WebhookEndpointId, &delivery.EventType, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &responseCode, &delivery.LastError, &delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	if responseCode.Valid {
+		code := int(responseCode.Int64)
+		delivery.LastResponseCode = &code
+	}
+
+	return &delivery, nil
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_endpoint_id = $1
+		ORDER BY created_at DESC, webhook_delivery_id DESC
+		LIMIT $2 OFFSET $3;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, endpointId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery row: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery rows: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) GetDeliveryById(ctx context.Context, deliveryId int64) (*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_delivery_id = $1;
+	`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, deliveryId).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	attemptsQuery := `
+		SELECT response_code, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE webhook_delivery_id = $1
+		ORDER BY attempted_at;
+	`
+
+	rows, err := r.db.QueryContext(ctx, attemptsQuery, deliveryId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attempt models.WebhookDeliveryAttempt
+		var responseCode sql.NullInt64
+		if err := rows.Scan(&responseCode, &attempt.Error, &attempt.DurationMs, &attempt.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery attempt row: %w", err)
+		}
+		if responseCode.Valid {
+			code := int(responseCode.Int64)
+			attempt.ResponseCode = &code
+		}
+		delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery attempt rows: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// ReplayDelivery queues a delivery again with a fresh retry budget, whatever
+// its outcome so far. Earlier attempts stay in the attempt log.
+func (r *webhookRepository) ReplayDelivery(ctx context.Context, deliveryId int64) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE webhook_delivery_id = $1;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, deliveryId)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
@@ -1,39 +1,148 @@
 package routes
 
 import (
+	"context"
 	"database/sql"
+	"log"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/config"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/http"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/metrics"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/middleware"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/database"
 	"github.com/go-redis/redis/v8"
 	"github.com/labstack/echo/v4"
 )
 
-func SetupRoutes(e *echo.Echo, db *sql.DB, redisClient *redis.Client) {
+// SetupRoutes registers every route and returns the health service, whose
+// readiness the server flips when it starts draining.
+func SetupRoutes(e *echo.Echo, db *sql.DB, redisClient *redis.Client, cfg *config.Config) services.HealthService {
 	apiV1 := e.Group("/v1")
 
 	redisRepo := repositories.NewRedisRepository(redisClient)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	listCache := repositories.NewListCache(redisRepo, cfg.Cache.ListTTL())
+
+	migrator, err := database.NewMigrator(db, cfg.Postgres.Schema)
+	if err != nil {
+		log.Fatalf("Could not load migrations: %v", err)
+	}
+
+	healthService := services.NewHealthService(db, migrator, redisRepo, outboxRepo, repositories.NewJobRepository(redisClient))
+	healthHandler := http.NewHealthHandler(healthService)
+	e.GET("/healthz", healthHandler.Liveness)
+	e.GET("/readyz", healthHandler.Readiness)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	tokenIssuer := cfg.JWT.TokenIssuer()
+	auth := middleware.AuthMiddleware(redisRepo, tokenIssuer)
+	// on POST and PATCH routes, after auth and before rate limits. Not on
+	// /auth, whose responses carry tokens that must not be kept in Redis.
+	idempotent := middleware.IdempotencyMiddleware(services.NewIdempotencyService(redisRepo))
+
+	rateLimitService := services.NewRateLimitService(redisRepo)
+	loginLimit := middleware.RateLimitMiddleware(rateLimitService, "login", cfg.RateLimit.LoginPolicy(), middleware.ByIP, middleware.ByUsername)
+	refreshLimit := middleware.RateLimitMiddleware(rateLimitService, "refresh", cfg.RateLimit.RefreshPolicy(), middleware.ByIP, middleware.ByUserId)
+	appointmentLimit := middleware.RateLimitMiddleware(rateLimitService, "appointment", cfg.RateLimit.AppointmentPolicy(), middleware.ByUserId)
+	bookingLimit := middleware.RateLimitMiddleware(rateLimitService, "booking", cfg.RateLimit.AppointmentPolicy(), middleware.ByIP)
 
 	userRepo := repositories.NewUserRepository(db)
-	userService := services.NewUserService(userRepo, redisRepo)
+	userService := services.NewUserService(userRepo, redisRepo, tokenIssuer, cfg.RateLimit.LockoutPolicy(), listCache)
 	userHandler := http.NewUserHandler(userService)
-	apiV1.POST("/auth/login", userHandler.Login)
-	apiV1.POST("/auth/refresh", userHandler.RefreshToken, middleware.AuthMiddleware(redisRepo))
+	apiV1.POST("/auth/login", userHandler.Login, loginLimit)
+	apiV1.POST("/auth/refresh", userHandler.RefreshToken, auth, refreshLimit)
 	apiV1.GET("/users", userHandler.GetUsers)
-	apiV1.PATCH("/users/timezone", userHandler.UpdateUserTimezone, middleware.AuthMiddleware(redisRepo))
+	apiV1.PATCH("/users/timezone", userHandler.UpdateUserTimezone, auth, idempotent)
+	apiV1.PATCH("/users/buffers", userHandler.UpdateUserBuffers, auth, idempotent)
+
+	appointmentRepo := repositories.NewCachedAppointmentRepository(repositories.NewAppointmentRepository(db), listCache)
+	auditRepo := repositories.NewAuditRepository(db)
+
+	webhookRepo := repositories.NewWebhookRepository(db)
+
+	realtimeService := services.NewRealtimeService(redisRepo)
+	go realtimeService.Run(context.Background())
+	realtimeHandler := http.NewRealtimeHandler(realtimeService)
+	apiV1.GET("/events/stream", realtimeHandler.Stream, middleware.QueryTokenMiddleware, auth)
 
-	invitationRepo := repositories.NewInvitationRepository(db)
-	invitationService := services.NewInvitationService(invitationRepo)
+	invitationRepo := repositories.NewCachedInvitationRepository(repositories.NewInvitationRepository(db), listCache)
+	invitationService := services.NewInvitationService(invitationRepo, appointmentRepo, auditRepo, outboxRepo, listCache)
 	invitationHandler := http.NewInvitationHandler(invitationService)
-	apiV1.GET("/invitations", invitationHandler.GetInvitations, middleware.AuthMiddleware(redisRepo))
-	apiV1.PATCH("/invitations/accept/:invitationId", invitationHandler.AcceptInvitation, middleware.AuthMiddleware(redisRepo))
-	apiV1.PATCH("/invitations/reject/:invitationId", invitationHandler.RejectInvitation, middleware.AuthMiddleware(redisRepo))
+	apiV1.GET("/invitations", invitationHandler.GetInvitations, auth)
+	apiV1.PATCH("/invitations/accept/:invitationId", invitationHandler.AcceptInvitation, auth, idempotent)
+	apiV1.PATCH("/invitations/reject/:invitationId", invitationHandler.RejectInvitation, auth, idempotent)
 
-	appointmentRepo := repositories.NewAppointmentRepository(db)
-	appointmentService := services.NewAppointmentService(appointmentRepo, invitationRepo)
+	resourceRepo := repositories.NewResourceRepository(db)
+	resourceService := services.NewResourceService(resourceRepo)
+	resourceHandler := http.NewResourceHandler(resourceService)
+	apiV1.GET("/resources", resourceHandler.GetResources, auth)
+	apiV1.POST("/resources", resourceHandler.CreateResource, auth, middleware.RoleMiddleware(models.UserRoleAdmin), idempotent)
+	apiV1.GET("/resources/:resourceId/busy", resourceHandler.GetResourceBusy, auth)
+	apiV1.GET("/resources/:resourceId/calendar", resourceHandler.GetResourceCalendar, auth)
+
+	meetingProvider, err := services.NewMeetingProvider(cfg.Meeting.Provider, cfg.Meeting.UrlTemplate)
+	if err != nil {
+		log.Fatalf("Could not configure meeting provider: %v", err)
+	}
+
+	appointmentService := services.NewAppointmentService(appointmentRepo, invitationRepo, resourceRepo, auditRepo, meetingProvider, outboxRepo, listCache)
 	appointmentHandler := http.NewAppointmentHandler(appointmentService)
-	apiV1.GET("/appointment", appointmentHandler.GetAppointments, middleware.AuthMiddleware(redisRepo))
-	apiV1.POST("/appointment", appointmentHandler.CreateAppointment, middleware.AuthMiddleware(redisRepo))
+	apiV1.GET("/appointment", appointmentHandler.GetAppointments, auth)
+	apiV1.POST("/appointment", appointmentHandler.CreateAppointment, auth, idempotent, appointmentLimit)
+	apiV1.GET("/users/:userId/busy", appointmentHandler.GetFreeBusy, auth)
+	apiV1.GET("/appointment/open", appointmentHandler.GetOpenAppointments, auth)
+	apiV1.GET("/appointment/search", appointmentHandler.SearchAppointments, auth)
+	apiV1.GET("/appointment/:appointmentId", appointmentHandler.GetAppointment, auth)
+	apiV1.GET("/appointment/:appointmentId/attendees", appointmentHandler.GetAttendees, auth)
+	apiV1.POST("/appointment/:appointmentId/join", appointmentHandler.JoinAppointment, auth, idempotent)
+	apiV1.GET("/appointment/:appointmentId/ics", appointmentHandler.ExportICS, auth)
+	apiV1.PATCH("/appointment/:appointmentId", appointmentHandler.UpdateAppointment, auth, idempotent)
+	apiV1.POST("/appointment/:appointmentId/cancel", appointmentHandler.CancelAppointment, auth, idempotent)
+	apiV1.POST("/appointment/:appointmentId/invitees", appointmentHandler.AddInvitees, auth, idempotent)
+	apiV1.DELETE("/appointment/:appointmentId/invitees/:inviteeId", appointmentHandler.RemoveInvitee, auth)
+
+	auditService := services.NewAuditService(auditRepo, appointmentRepo)
+	auditHandler := http.NewAuditHandler(auditService)
+	apiV1.GET("/appointment/:appointmentId/history", auditHandler.GetAppointmentHistory, auth)
+	apiV1.GET("/audit-events", auditHandler.SearchAuditEvents, auth, middleware.RoleMiddleware(models.UserRoleAdmin))
+
+	timeOffRepo := repositories.NewTimeOffRepository(db)
+	holidayRepo := repositories.NewHolidayRepository(db)
+	timeOffService := services.NewTimeOffService(timeOffRepo)
+	holidayService := services.NewHolidayService(holidayRepo)
+	timeOffHandler := http.NewTimeOffHandler(timeOffService, holidayService)
+	apiV1.GET("/time-off", timeOffHandler.GetTimeOff, auth)
+	apiV1.POST("/time-off", timeOffHandler.CreateTimeOff, auth, idempotent)
+	apiV1.DELETE("/time-off/:timeOffId", timeOffHandler.DeleteTimeOff, auth)
+	apiV1.GET("/holidays", timeOffHandler.GetHolidays, auth)
+	apiV1.GET("/holiday-calendars", timeOffHandler.GetHolidayCalendars, auth)
+	apiV1.POST("/holiday-calendars", timeOffHandler.CreateHolidayCalendar, auth, middleware.RoleMiddleware(models.UserRoleAdmin), idempotent)
+	apiV1.POST("/holiday-calendars/:calendarId/import", timeOffHandler.ImportHolidays, auth, middleware.RoleMiddleware(models.UserRoleAdmin), idempotent)
+	apiV1.GET("/holiday-calendars/:calendarId/users", timeOffHandler.GetHolidayCalendarUsers, auth, middleware.RoleMiddleware(models.UserRoleAdmin))
+	apiV1.POST("/holiday-calendars/:calendarId/users", timeOffHandler.AssignHolidayCalendarUsers, auth, middleware.RoleMiddleware(models.UserRoleAdmin), idempotent)
+	apiV1.DELETE("/holiday-calendars/:calendarId/users/:userId", timeOffHandler.UnassignHolidayCalendarUser, auth, middleware.RoleMiddleware(models.UserRoleAdmin))
+
+	bookingRepo := repositories.NewBookingRepository(db)
+	bookingService := services.NewBookingService(bookingRepo, appointmentRepo, auditRepo, outboxRepo, listCache)
+	bookingHandler := http.NewBookingHandler(bookingService)
+	apiV1.GET("/booking-pages", bookingHandler.GetBookingPages, auth)
+	apiV1.POST("/booking-pages", bookingHandler.CreateBookingPage, auth, idempotent)
+	apiV1.GET("/book/:slug/slots", bookingHandler.GetSlots)
+	apiV1.POST("/book/:slug", bookingHandler.Book, idempotent, bookingLimit)
+
+	webhookService := services.NewWebhookService(webhookRepo, nil)
+	webhookHandler := http.NewWebhookHandler(webhookService)
+	admin := []echo.MiddlewareFunc{auth, middleware.RoleMiddleware(models.UserRoleAdmin)}
+	adminIdempotent := []echo.MiddlewareFunc{auth, middleware.RoleMiddleware(models.UserRoleAdmin), idempotent}
+	apiV1.GET("/webhooks", webhookHandler.GetEndpoints, admin...)
+	apiV1.POST("/webhooks", webhookHandler.CreateEndpoint, adminIdempotent...)
+	apiV1.DELETE("/webhooks/:webhookId", webhookHandler.DeactivateEndpoint, admin...)
+	apiV1.GET("/webhooks/:webhookId/deliveries", webhookHandler.GetDeliveries, admin...)
+	apiV1.GET("/webhook-deliveries/:deliveryId", webhookHandler.GetDelivery, admin...)
+	apiV1.POST("/webhook-deliveries/:deliveryId/replay", webhookHandler.ReplayDelivery, adminIdempotent...)
+
+	return healthService
 }
@@ -1,42 +1,119 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+var (
+	ErrAppointmentNotOpen   = apperror.Conflict("appointment is not open for sign-up")
+	ErrAlreadyJoined        = apperror.Conflict("user already joined the appointment")
+	ErrVideoMeetingOff      = apperror.Validation("video meetings are not enabled")
+	ErrNotAppointmentHost   = apperror.Forbidden("only the host can change the appointment")
+	ErrAlreadyInvited       = apperror.Conflict("user is already invited to the appointment")
+	ErrInvalidTimeRange     = apperror.Validation("end time must be after start time")
+	ErrAppointmentCancelled = apperror.Conflict("appointment is cancelled")
+	ErrDuplicateInvitee     = apperror.Validation("an invitee is listed more than once or is the host")
+	ErrQuorumUnreachable    = apperror.Validation("quorum is more than the required invitees and the host")
 )
 
 type AppointmentService interface {
-	CreateAppointment(appointment *models.Appointment) (*models.Appointment, error)
-	GetAppointmentsByUserId(userId int) ([]models.AppointmentInvitation, error)
+	CreateAppointment(ctx context.Context, appointment *models.Appointment) (*models.Appointment, error)
+	GetAppointment(ctx context.Context, userId int, appointmentId int) (*models.Appointment, error)
+	// UpdateAppointment, AddInvitees, RemoveInvitee and CancelAppointment are
+	// refused with ErrAppointmentModified unless the appointment is still at
+	// version, 0 skips the check. AddInvitees and RemoveInvitee return the
+	// version they moved the appointment to.
+	UpdateAppointment(ctx context.Context, userId int, appointmentId int, version int, patch models.AppointmentPatch) (*models.Appointment, error)
+	AddInvitees(ctx context.Context, userId int, appointmentId int, version int, role string, inviteeIds []int) (int, []models.Invitation, []string, error)
+	RemoveInvitee(ctx context.Context, userId int, appointmentId int, version int, inviteeId int) (int, error)
+	CancelAppointment(ctx context.Context, userId int, appointmentId int, version int) error
+	GetAppointmentsByUserId(ctx context.Context, userId int) ([]models.AppointmentInvitation, error)
+	GetAttendees(ctx context.Context, userId int, appointmentId int, filter models.AttendeeFilter) (*models.AttendeePage, error)
+	SearchAppointments(ctx context.Context, userId int, filter models.AppointmentSearch) ([]models.AppointmentSearchResult, error)
+
+	GetFreeBusy(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.TimeSlot, error)
+
+	GetOpenAppointments(ctx context.Context, userId int) ([]models.OpenAppointment, error)
+	JoinAppointment(ctx context.Context, userId int, appointmentId int) (*models.Invitation, error)
+
+	ExportICS(ctx context.Context, userId int, appointmentId int) (string, error)
 }
 
 type appointmentService struct {
 	appointmentRepository repositories.AppointmentRepository
 	invitationRepository  repositories.InvitationRepository
+	resourceRepository    repositories.ResourceRepository
+	auditRepository       repositories.AuditRepository
+	meetingProvider       MeetingProvider
+	outboxRepository      repositories.OutboxRepository
+	listCache             repositories.ListCache
 }
 
-func NewAppointmentService(appointmentRepository repositories.AppointmentRepository, invitationRepository repositories.InvitationRepository) AppointmentService {
+func NewAppointmentService(appointmentRepository repositories.AppointmentRepository, invitationRepository repositories.InvitationRepository, resourceRepository repositories.ResourceRepository, auditRepository repositories.AuditRepository, meetingProvider MeetingProvider, outboxRepository repositories.OutboxRepository, listCache repositories.ListCache) AppointmentService {
 	return &appointmentService{
 		appointmentRepository: appointmentRepository,
 		invitationRepository:  invitationRepository,
+		resourceRepository:    resourceRepository,
+		auditRepository:       auditRepository,
+		meetingProvider:       meetingProvider,
+		outboxRepository:      outboxRepository,
+		listCache:             listCache,
 	}
 }
 
-func (s *appointmentService) CreateAppointment(appointment *models.Appointment) (*models.Appointment, error) {
+func (s *appointmentService) CreateAppointment(ctx context.Context, appointment *models.Appointment) (_ *models.Appointment, err error) {
+
+	if appointment.VideoMeeting && s.meetingProvider == nil {
+		return nil, ErrVideoMeetingOff
+	}
+
+	if err := checkInviteeIds(appointment.HostId, appointment.InviteeIds, appointment.OptionalInviteeIds, appointment.FyiInviteeIds); err != nil {
+		return nil, err
+	}
+
+	// the derived status could never reach confirmed
+	if quorum := appointment.Quorum; quorum != nil && (*quorum < 0 || *quorum > len(appointment.InviteeIds)+1) {
+		return nil, ErrQuorumUnreachable.WithDetails(map[string]interface{}{"max_quorum": len(appointment.InviteeIds) + 1})
+	}
+
+	capacityWarnings, err := s.checkResourceCapacity(ctx, appointment)
+	if err != nil {
+		return nil, err
+	}
 
-	tx, err := s.appointmentRepository.BeginAppointmentTx()
+	tx, err := s.appointmentRepository.BeginAppointmentTx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error create appointment: %w", err)
 	}
 
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
 	defer func() {
 		if p := recover(); p != nil {
 			tx.Rollback()
-			log.Printf("Recovered from panic: %v", p)
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
 			panic(p)
 		} else if err != nil {
 			tx.Rollback()
@@ -48,34 +125,711 @@ func (s *appointmentService) CreateAppointment(appointment *models.Appointment)
 		}
 	}()
 
+	err = s.appointmentRepository.LockSchedules(ctx, tx, blockingUserIds(appointment))
+	if err != nil {
+		return nil, err
+	}
+
+	warnings, autoReplies, err := s.checkConflicts(ctx, tx, appointment)
+	if err != nil {
+		return nil, err
+	}
+	appointment.Warnings = append(warnings, capacityWarnings...)
+
 	appointment.CreatedAt = time.Now().UTC()
 
-	createdAppointment, err := s.appointmentRepository.InsertAppointment(tx, appointment)
+	createdAppointment, err := s.appointmentRepository.InsertAppointment(ctx, tx, appointment)
 	if err != nil {
 		return nil, err
 	}
 
 	var invitees []models.Invitation
-	for _, item := range appointment.InviteeIds {
-		invite := models.Invitation{
-			AppointmentId: createdAppointment.AppointmentId,
-			InviteeId:     item,
-			Status:        "pending",
-			Notes:         "",
-			CreatedAt:     time.Now(),
+	roles := map[string][]int{
+		models.InvitationRoleRequired: appointment.InviteeIds,
+		models.InvitationRoleOptional: appointment.OptionalInviteeIds,
+		models.InvitationRoleFyi:      appointment.FyiInviteeIds,
+	}
+	for role, ids := range roles {
+		for _, item := range ids {
+			invite := models.Invitation{
+				AppointmentId: createdAppointment.AppointmentId,
+				InviteeId:     item,
+				Role:          role,
+				Status:        "pending",
+				Notes:         autoReplies[item],
+				CreatedAt:     time.Now(),
+			}
+			invitees = append(invitees, invite)
+		}
+	}
+
+	err = s.invitationRepository.InsertInvitation(ctx, tx, invitees)
+	if err != nil {
+		return nil, err
+	}
+	var inviteeIds []int
+	for _, invite := range invitees {
+		inviteeIds = append(inviteeIds, invite.InviteeId)
+	}
+
+	err = s.resourceRepository.BookResources(ctx, tx, createdAppointment.AppointmentId, appointment.ResourceIds, appointment.StartTime, appointment.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// the link is generated last so a failing provider rolls the appointment back
+	if appointment.VideoMeeting {
+		var joinUrl string
+		joinUrl, err = s.meetingProvider.CreateMeeting(createdAppointment)
+		if err != nil {
+			return nil, fmt.Errorf("error create video meeting: %w", err)
 		}
-		invitees = append(invitees, invite)
+
+		err = s.appointmentRepository.UpdateMeetingLink(ctx, tx, createdAppointment.AppointmentId, s.meetingProvider.Name(), joinUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		createdAppointment.MeetingProvider = s.meetingProvider.Name()
+		createdAppointment.JoinUrl = joinUrl
+	}
+
+	after := appointmentSnapshot(createdAppointment)
+	after["invitee_ids"] = appointment.InviteeIds
+	after["optional_invitee_ids"] = appointment.OptionalInviteeIds
+	after["fyi_invitee_ids"] = appointment.FyiInviteeIds
+	after["resource_ids"] = appointment.ResourceIds
+
+	err = recordAudit(ctx, s.auditRepository, tx, createdAppointment.AppointmentId, models.AuditEntityAppointment, createdAppointment.AppointmentId,
+		models.AuditActionAppointmentCreated, &appointment.HostId, nil, after)
+	if err != nil {
+		return nil, err
 	}
 
-	err = s.invitationRepository.InsertInvitation(tx, invitees)
+	err = recordOutbox(ctx, s.outboxRepository, tx, models.RealtimeInvitationReceived, createdAppointment.AppointmentId, inviteeIds, createdAppointment)
 	if err != nil {
 		return nil, err
 	}
 
+	err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventAppointmentCreated, createdAppointment.AppointmentId, nil, createdAppointment)
+	if err != nil {
+		return nil, err
+	}
+
+	affected = append([]int{appointment.HostId}, inviteeIds...)
+
 	return createdAppointment, nil
 }
 
-func (s *appointmentService) GetAppointmentsByUserId(userId int) ([]models.AppointmentInvitation, error) {
+// UpdateAppointment applies the host's changes. A new time is checked against
+// the schedules of the current participants and moves the resource bookings.
+func (s *appointmentService) UpdateAppointment(ctx context.Context, userId int, appointmentId int, version int, patch models.AppointmentPatch) (_ *models.Appointment, err error) {
+
+	tx, err := s.appointmentRepository.BeginAppointmentTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error update appointment: %w", err)
+	}
+
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	appointment, err := s.appointmentRepository.LockAppointment(ctx, tx, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.HostId != userId {
+		err = ErrNotAppointmentHost
+		return nil, err
+	}
+
+	if appointment.CancelledAt != nil {
+		err = ErrAppointmentCancelled
+		return nil, err
+	}
+
+	if err = checkAppointmentVersion(appointment, version); err != nil {
+		return nil, err
+	}
+
+	before := appointmentSnapshot(appointment)
+
+	if patch.Title != nil {
+		appointment.Title = *patch.Title
+	}
+	if patch.Description != nil {
+		appointment.Description = *patch.Description
+	}
+	if patch.Location != nil {
+		appointment.Location = *patch.Location
+	}
+	if patch.TravelMinutes != nil {
+		appointment.TravelMinutes = *patch.TravelMinutes
+	}
+	if patch.StartTime != nil {
+		appointment.StartTime = patch.StartTime.UTC()
+	}
+	if patch.EndTime != nil {
+		appointment.EndTime = patch.EndTime.UTC()
+	}
+	if patch.Tags != nil {
+		appointment.Tags = *patch.Tags
+	}
+
+	if !appointment.EndTime.After(appointment.StartTime) {
+		err = ErrInvalidTimeRange
+		return nil, err
+	}
+
+	after := appointmentSnapshot(appointment)
+	rescheduled := before["start_time"] != after["start_time"] || before["end_time"] != after["end_time"]
+
+	invitations, err := s.invitationRepository.GetInvitationsByAppointment(ctx, tx, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if rescheduled || before["travel_minutes"] != after["travel_minutes"] {
+		for _, inv := range invitations {
+			if inv.Status == models.InvitationStatusRejected {
+				continue
+			}
+			switch inv.Role {
+			case models.InvitationRoleOptional:
+				appointment.OptionalInviteeIds = append(appointment.OptionalInviteeIds, inv.InviteeId)
+			case models.InvitationRoleFyi:
+				appointment.FyiInviteeIds = append(appointment.FyiInviteeIds, inv.InviteeId)
+			default:
+				appointment.InviteeIds = append(appointment.InviteeIds, inv.InviteeId)
+			}
+		}
+
+		err = s.appointmentRepository.LockSchedules(ctx, tx, blockingUserIds(appointment))
+		if err != nil {
+			return nil, err
+		}
+
+		appointment.Warnings, _, err = s.checkConflicts(ctx, tx, appointment)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.resourceRepository.RescheduleResources(ctx, tx, appointmentId, appointment.StartTime, appointment.EndTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.appointmentRepository.UpdateAppointment(ctx, tx, appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	action := models.AuditActionAppointmentUpdated
+	if rescheduled {
+		action = models.AuditActionAppointmentRescheduled
+	}
+
+	err = recordAudit(ctx, s.auditRepository, tx, appointmentId, models.AuditEntityAppointment, appointmentId, action, &userId, before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventAppointmentUpdated, appointmentId,
+		participantIds(appointment.HostId, invitations, true), appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	affected = participantIds(appointment.HostId, invitations, false)
+
+	return appointment, nil
+}
+
+// AddInvitees invites more users with the given role. Busy required invitees
+// block like on creation, other roles only produce warnings.
+func (s *appointmentService) AddInvitees(ctx context.Context, userId int, appointmentId int, version int, role string, inviteeIds []int) (_ int, _ []models.Invitation, _ []string, err error) {
+	inviteeIds = utils.UniqueInts(inviteeIds)
+
+	tx, err := s.invitationRepository.BeginInvitationTx(ctx)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("error add invitees: %w", err)
+	}
+
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	appointment, err := s.appointmentRepository.LockAppointment(ctx, tx, appointmentId)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	if appointment.HostId != userId {
+		err = ErrNotAppointmentHost
+		return 0, nil, nil, err
+	}
+
+	if appointment.CancelledAt != nil {
+		err = ErrAppointmentCancelled
+		return 0, nil, nil, err
+	}
+
+	if err = checkAppointmentVersion(appointment, version); err != nil {
+		return 0, nil, nil, err
+	}
+
+	for _, id := range inviteeIds {
+		_, err = s.invitationRepository.GetInvitationByInvitee(ctx, tx, appointmentId, id)
+		if err == nil || id == appointment.HostId {
+			err = ErrAlreadyInvited
+			return 0, nil, nil, err
+		}
+		if !errors.Is(err, repositories.ErrInvitationNotFound) {
+			return 0, nil, nil, err
+		}
+	}
+
+	switch role {
+	case models.InvitationRoleOptional:
+		appointment.OptionalInviteeIds = inviteeIds
+	case models.InvitationRoleFyi:
+		appointment.FyiInviteeIds = inviteeIds
+	default:
+		appointment.InviteeIds = inviteeIds
+	}
+
+	err = s.appointmentRepository.LockSchedules(ctx, tx, blockingUserIds(appointment))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	warnings, autoReplies, err := s.checkConflicts(ctx, tx, appointment)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	var invitees []models.Invitation
+	for _, id := range inviteeIds {
+		invitees = append(invitees, models.Invitation{
+			AppointmentId: appointmentId,
+			InviteeId:     id,
+			Role:          role,
+			Status:        models.InvitationStatusPending,
+			Notes:         autoReplies[id],
+			CreatedAt:     time.Now(),
+		})
+	}
+
+	err = s.invitationRepository.InsertInvitation(ctx, tx, invitees)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	err = s.appointmentRepository.BumpAppointmentVersion(ctx, tx, appointment)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	var invitations []models.Invitation
+	for _, id := range inviteeIds {
+		var invitation *models.Invitation
+		invitation, err = s.invitationRepository.GetInvitationByInvitee(ctx, tx, appointmentId, id)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		err = recordAudit(ctx, s.auditRepository, tx, appointmentId, models.AuditEntityInvitation, invitation.InvitationId,
+			models.AuditActionInviteeAdded, &userId, nil, invitationSnapshot(invitation))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventInvitationCreated, appointmentId, nil, invitation)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		invitations = append(invitations, *invitation)
+	}
+
+	err = recordOutbox(ctx, s.outboxRepository, tx, models.RealtimeInvitationReceived, appointmentId, inviteeIds, appointment)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	affected, err = appointmentUserIds(ctx, s.invitationRepository, tx, appointment)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return appointment.Version, invitations, warnings, nil
+}
+
+// RemoveInvitee withdraws an invitation. A seat freed on an open appointment
+// goes to the first waitlisted user.
+func (s *appointmentService) RemoveInvitee(ctx context.Context, userId int, appointmentId int, version int, inviteeId int) (_ int, err error) {
+
+	tx, err := s.invitationRepository.BeginInvitationTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error remove invitee: %w", err)
+	}
+
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	appointment, err := s.appointmentRepository.LockAppointment(ctx, tx, appointmentId)
+	if err != nil {
+		return 0, err
+	}
+
+	if appointment.HostId != userId {
+		err = ErrNotAppointmentHost
+		return 0, err
+	}
+
+	if appointment.CancelledAt != nil {
+		err = ErrAppointmentCancelled
+		return 0, err
+	}
+
+	if err = checkAppointmentVersion(appointment, version); err != nil {
+		return 0, err
+	}
+
+	invitation, err := s.invitationRepository.GetInvitationByInvitee(ctx, tx, appointmentId, inviteeId)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.invitationRepository.DeleteInvitation(ctx, tx, invitation.InvitationId)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.appointmentRepository.BumpAppointmentVersion(ctx, tx, appointment)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordAudit(ctx, s.auditRepository, tx, appointmentId, models.AuditEntityInvitation, invitation.InvitationId,
+		models.AuditActionInviteeRemoved, &userId, invitationSnapshot(invitation), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventInvitationRevoked, appointmentId, []int{inviteeId}, invitation)
+	if err != nil {
+		return 0, err
+	}
+
+	if appointment.IsOpen && invitation.Status == models.InvitationStatusAccepted {
+		var promoted *models.Invitation
+		promoted, err = promoteWaitlisted(ctx, s.invitationRepository, s.auditRepository, tx, appointment)
+		if err != nil {
+			return 0, err
+		}
+		if promoted != nil {
+			err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventInvitationAccepted, appointmentId,
+				[]int{appointment.HostId, promoted.InviteeId}, promoted)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	affected, err = appointmentUserIds(ctx, s.invitationRepository, tx, appointment)
+	if err != nil {
+		return 0, err
+	}
+	affected = append(affected, inviteeId)
+
+	return appointment.Version, nil
+}
+
+// CancelAppointment marks the appointment cancelled and frees its resources.
+// Invitations are kept for the history.
+func (s *appointmentService) CancelAppointment(ctx context.Context, userId int, appointmentId int, version int) (err error) {
+
+	tx, err := s.appointmentRepository.BeginAppointmentTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error cancel appointment: %w", err)
+	}
+
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	appointment, err := s.appointmentRepository.LockAppointment(ctx, tx, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	if appointment.HostId != userId {
+		err = ErrNotAppointmentHost
+		return err
+	}
+
+	if appointment.CancelledAt != nil {
+		err = ErrAppointmentCancelled
+		return err
+	}
+
+	if err = checkAppointmentVersion(appointment, version); err != nil {
+		return err
+	}
+
+	cancelledAt := time.Now().UTC()
+
+	err = s.appointmentRepository.CancelAppointment(ctx, tx, appointment, cancelledAt)
+	if err != nil {
+		return err
+	}
+
+	err = s.resourceRepository.ReleaseResources(ctx, tx, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(ctx, s.auditRepository, tx, appointmentId, models.AuditEntityAppointment, appointmentId,
+		models.AuditActionAppointmentCancelled, &userId,
+		map[string]interface{}{"cancelled_at": nil},
+		map[string]interface{}{"cancelled_at": cancelledAt.Format(time.RFC3339)})
+	if err != nil {
+		return err
+	}
+
+	invitations, err := s.invitationRepository.GetInvitationsByAppointment(ctx, tx, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventAppointmentCancelled, appointmentId,
+		participantIds(appointment.HostId, invitations, false), appointment)
+	if err != nil {
+		return err
+	}
+
+	affected = participantIds(appointment.HostId, invitations, false)
+
+	return nil
+}
+
+// checkInviteeIds refuses an invitee listed twice, in one role or across
+// roles, and the host inviting themselves. An appointment holds one
+// invitation per user.
+func checkInviteeIds(hostId int, roles ...[]int) error {
+	seen := map[int]bool{hostId: true}
+	var duplicates []int
+
+	for _, ids := range roles {
+		for _, id := range ids {
+			if seen[id] {
+				duplicates = append(duplicates, id)
+			}
+			seen[id] = true
+		}
+	}
+
+	if len(duplicates) > 0 {
+		return ErrDuplicateInvitee.WithDetails(map[string]interface{}{"invitee_ids": utils.UniqueInts(duplicates)})
+	}
+
+	return nil
+}
+
+// checkResourceCapacity warns when more people are invited than a resource fits.
+func (s *appointmentService) checkResourceCapacity(ctx context.Context, appointment *models.Appointment) ([]string, error) {
+	participants := 1 + len(appointment.InviteeIds) + len(appointment.OptionalInviteeIds)
+
+	var warnings []string
+	for _, id := range appointment.ResourceIds {
+		resource, err := s.resourceRepository.GetResourceById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if resource.Capacity != nil && participants > *resource.Capacity {
+			warnings = append(warnings, fmt.Sprintf("resource %s fits %d people, %d are invited", resource.Name, *resource.Capacity, participants))
+		}
+	}
+
+	return warnings, nil
+}
+
+// blockingUserIds lists the users whose busy time blocks the appointment, whose
+// schedule locks checkConflicts has to run under.
+func blockingUserIds(appointment *models.Appointment) []int {
+	return append([]int{appointment.HostId}, appointment.InviteeIds...)
+}
+
+// checkConflicts hard-blocks on the host and required attendees being busy or
+// away. Optional attendees only produce warnings, FYI attendees only when they
+// are away. Travel time of the new appointment and the users' buffers count as
+// busy. Away invitees are returned with the auto-reply for their invitation.
+// It reads in tx, which holds the locks of blockingUserIds, so two
+// appointments for the same people cannot both pass.
+func (s *appointmentService) checkConflicts(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) ([]string, map[int]string, error) {
+	duration := appointment.EndTime.Sub(appointment.StartTime)
+	travel := time.Duration(appointment.TravelMinutes) * time.Minute
+	start := appointment.StartTime.Add(-travel)
+	end := appointment.EndTime.Add(travel)
+
+	roles := map[int]string{appointment.HostId: "host"}
+	for _, id := range appointment.InviteeIds {
+		roles[id] = models.InvitationRoleRequired
+	}
+	for _, id := range appointment.OptionalInviteeIds {
+		if _, ok := roles[id]; !ok {
+			roles[id] = models.InvitationRoleOptional
+		}
+	}
+	for _, id := range appointment.FyiInviteeIds {
+		if _, ok := roles[id]; !ok {
+			roles[id] = models.InvitationRoleFyi
+		}
+	}
+
+	userIds := make([]int, 0, len(roles))
+	for id := range roles {
+		userIds = append(userIds, id)
+	}
+
+	busy, err := s.appointmentRepository.GetBusyIntervalsTx(ctx, tx, userIds, start, end.Add(suggestionWindow))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error check conflicts: %w", err)
+	}
+
+	var blocking []models.BusyInterval
+	var conflicts []models.AppointmentConflict
+	var warnings []string
+	autoReplies := make(map[int]string)
+
+	for _, b := range busy {
+		// an existing appointment being changed does not conflict with itself
+		if b.Kind == models.BusyKindAppointment && b.AppointmentId == appointment.AppointmentId {
+			continue
+		}
+
+		role := roles[b.UserId]
+		away := b.Kind != models.BusyKindAppointment
+
+		if role == models.InvitationRoleOptional || role == models.InvitationRoleFyi {
+			if !collides(b, start, end) {
+				continue
+			}
+
+			if away {
+				warnings = append(warnings, fmt.Sprintf("%s attendee %d is away (%s)", role, b.UserId, awayMessage(b)))
+				autoReplies[b.UserId] = awayMessage(b)
+			} else if role == models.InvitationRoleOptional {
+				warnings = append(warnings, fmt.Sprintf("optional attendee %d has a conflicting appointment %d", b.UserId, b.AppointmentId))
+			}
+			continue
+		}
+
+		blocking = append(blocking, b)
+		if collides(b, start, end) {
+			conflicts = append(conflicts, models.AppointmentConflict{
+				UserId:        b.UserId,
+				Role:          role,
+				Kind:          b.Kind,
+				AppointmentId: b.AppointmentId,
+				StartTime:     b.StartTime,
+				EndTime:       b.EndTime,
+			})
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, nil, &AppointmentConflictError{
+			Conflicts:   conflicts,
+			Suggestions: suggestSlots(blocking, appointment.StartTime, appointment.StartTime.Add(suggestionWindow+duration), duration, travel, suggestionStep, suggestionLimit),
+		}
+	}
+
+	return warnings, autoReplies, nil
+}
+
+func (s *appointmentService) GetAppointmentsByUserId(ctx context.Context, userId int) ([]models.AppointmentInvitation, error) {
 	date := "2025-02-13"
 
 	parsedDate, err := time.Parse("2006-01-02", date)
@@ -86,5 +840,240 @@ func (s *appointmentService) GetAppointmentsByUserId(userId int) ([]models.Appoi
 
 	endDate := parsedDate.AddDate(0, 0, 4)
 
-	return s.appointmentRepository.GetAppointmentsByUserId(userId, parsedDate, endDate)
+	return s.appointmentRepository.GetAppointmentsByUserId(ctx, userId, parsedDate, endDate)
+}
+
+// GetFreeBusy returns the merged busy periods of a user, including travel time
+// and buffers, without revealing which appointments they belong to.
+func (s *appointmentService) GetFreeBusy(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.TimeSlot, error) {
+	busy, err := s.appointmentRepository.GetBusyIntervals(ctx, []int{userId}, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeBusy(busy), nil
+}
+
+func (s *appointmentService) GetOpenAppointments(ctx context.Context, userId int) ([]models.OpenAppointment, error) {
+	return s.appointmentRepository.GetOpenAppointments(ctx, userId, time.Now().UTC())
+}
+
+// JoinAppointment signs the user up to an open appointment. The appointment row
+// lock keeps concurrent joins from exceeding capacity; late joiners are waitlisted.
+func (s *appointmentService) JoinAppointment(ctx context.Context, userId int, appointmentId int) (_ *models.Invitation, err error) {
+
+	tx, err := s.invitationRepository.BeginInvitationTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error join appointment: %w", err)
+	}
+
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	appointment, err := s.appointmentRepository.LockAppointment(ctx, tx, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !appointment.IsOpen || appointment.CancelledAt != nil {
+		err = ErrAppointmentNotOpen
+		return nil, err
+	}
+
+	if appointment.HostId == userId {
+		err = ErrAlreadyJoined
+		return nil, err
+	}
+
+	status, err := seatStatus(ctx, s.invitationRepository, tx, appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	var before map[string]interface{}
+
+	invitation, err := s.invitationRepository.GetInvitationByInvitee(ctx, tx, appointmentId, userId)
+	switch {
+	case err == nil:
+		if invitation.Status == models.InvitationStatusAccepted || invitation.Status == models.InvitationStatusWaitlisted {
+			err = ErrAlreadyJoined
+			return nil, err
+		}
+		before = invitationSnapshot(invitation)
+
+		// a pending or previously rejected invitation is reused for the sign-up
+		err = s.invitationRepository.UpdateStatusInvitation(ctx, tx, invitation, status)
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, repositories.ErrInvitationNotFound):
+		err = s.invitationRepository.InsertInvitation(ctx, tx, []models.Invitation{{
+			AppointmentId: appointmentId,
+			InviteeId:     userId,
+			Role:          models.InvitationRoleOptional,
+			Status:        status,
+			CreatedAt:     time.Now(),
+		}})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	invitation, err = s.invitationRepository.GetInvitationByInvitee(ctx, tx, appointmentId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	action := models.AuditActionInviteeAdded
+	if before != nil {
+		action = models.AuditActionInvitationStatus
+	}
+
+	err = recordAudit(ctx, s.auditRepository, tx, appointmentId, models.AuditEntityInvitation, invitation.InvitationId,
+		action, &userId, before, invitationSnapshot(invitation))
+	if err != nil {
+		return nil, err
+	}
+	err = recordOutbox(ctx, s.outboxRepository, tx, invitationWebhookEvent(invitation.Status), appointmentId,
+		[]int{appointment.HostId, userId}, invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err = appointmentUserIds(ctx, s.invitationRepository, tx, appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetAppointment returns an appointment the user hosts or is invited to.
+func (s *appointmentService) GetAppointment(ctx context.Context, userId int, appointmentId int) (*models.Appointment, error) {
+	return s.appointmentRepository.GetAppointmentForUser(ctx, userId, appointmentId)
+}
+
+// GetAttendees pages through the attendees of an appointment the user hosts or
+// is invited to.
+func (s *appointmentService) GetAttendees(ctx context.Context, userId int, appointmentId int, filter models.AttendeeFilter) (*models.AttendeePage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageLimit
+	}
+	if filter.Limit > maxPageLimit {
+		filter.Limit = maxPageLimit
+	}
+
+	_, err := s.appointmentRepository.GetAppointmentForUser(ctx, userId, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	attendees, err := s.invitationRepository.GetAttendees(ctx, appointmentId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.invitationRepository.CountAttendees(ctx, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	total := counts.Total
+	switch filter.Status {
+	case models.InvitationStatusAccepted:
+		total = counts.Accepted
+	case models.InvitationStatusPending:
+		total = counts.Pending
+	case models.InvitationStatusRejected:
+		total = counts.Rejected
+	case models.InvitationStatusWaitlisted:
+		total = counts.Waitlisted
+	}
+
+	return &models.AttendeePage{
+		Attendees: attendees,
+		Counts:    *counts,
+		Total:     total,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+	}, nil
+}
+
+// SearchAppointments searches the appointments the user hosts or is invited to.
+func (s *appointmentService) SearchAppointments(ctx context.Context, userId int, filter models.AppointmentSearch) ([]models.AppointmentSearchResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageLimit
+	}
+	if filter.Limit > maxPageLimit {
+		filter.Limit = maxPageLimit
+	}
+
+	return s.appointmentRepository.SearchAppointments(ctx, userId, filter)
+}
+
+// checkAppointmentVersion refuses a change to the locked appointment made
+// from a stale read, telling the client the current version. Version 0
+// skips the check.
+func checkAppointmentVersion(appointment *models.Appointment, version int) error {
+	if version != 0 && appointment.Version != version {
+		return repositories.ErrAppointmentModified.WithDetails(map[string]interface{}{"version": appointment.Version})
+	}
+
+	return nil
+}
+
+// appointmentUserIds lists the host and every invitee of the locked
+// appointment, whose cached lists show it.
+func appointmentUserIds(ctx context.Context, invitationRepository repositories.InvitationRepository, tx *sql.Tx, appointment *models.Appointment) ([]int, error) {
+	invitations, err := invitationRepository.GetInvitationsByAppointment(ctx, tx, appointment.AppointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return participantIds(appointment.HostId, invitations, false), nil
+}
+
+// ExportICS renders an appointment the user takes part in as an iCalendar file.
+func (s *appointmentService) ExportICS(ctx context.Context, userId int, appointmentId int) (string, error) {
+	appointment, err := s.appointmentRepository.GetAppointmentForUser(ctx, userId, appointmentId)
+	if err != nil {
+		return "", err
+	}
+
+	description := utils.StripHTML(appointment.Description)
+	if appointment.JoinUrl != "" {
+		description = strings.TrimSpace(description + "\n\nJoin: " + appointment.JoinUrl)
+	}
+
+	return utils.BuildICS([]utils.ICSEvent{{
+		Uid:         fmt.Sprintf("appointment-%d@be-appointment-system", appointment.AppointmentId),
+		Summary:     appointment.Title,
+		Description: description,
+		Location:    appointment.Location,
+		URL:         appointment.JoinUrl,
+		Start:       appointment.StartTime,
+		End:         appointment.EndTime,
+	}}), nil
 }
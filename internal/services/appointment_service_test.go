@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/go-redis/redis/v8"
+)
+
+// The fakes implement what RemoveInvitee needs, the embedded interfaces
+// panic on anything else.
+
+type fakeAppointmentRepository struct {
+	repositories.AppointmentRepository
+	appointment *models.Appointment
+}
+
+func (r *fakeAppointmentRepository) LockAppointment(ctx context.Context, tx *sql.Tx, appointmentId int) (*models.Appointment, error) {
+	return r.appointment, nil
+}
+
+func (r *fakeAppointmentRepository) BumpAppointmentVersion(ctx context.Context, tx *sql.Tx, appointment *models.Appointment) error {
+	appointment.Version++
+	return nil
+}
+
+type fakeInvitationRepository struct {
+	repositories.InvitationRepository
+	db          *sql.DB
+	invitations []models.Invitation
+	deleted     map[int]bool
+}
+
+func (r *fakeInvitationRepository) BeginInvitationTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *fakeInvitationRepository) GetInvitationByInvitee(ctx context.Context, tx *sql.Tx, appointmentId int, inviteeId int) (*models.Invitation, error) {
+	for i := range r.invitations {
+		if r.invitations[i].InviteeId == inviteeId {
+			return &r.invitations[i], nil
+		}
+	}
+	return nil, repositories.ErrInvitationNotFound
+}
+
+func (r *fakeInvitationRepository) DeleteInvitation(ctx context.Context, tx *sql.Tx, invId int) error {
+	r.deleted[invId] = true
+	return nil
+}
+
+func (r *fakeInvitationRepository) GetInvitationsByAppointment(ctx context.Context, tx *sql.Tx, appointmentId int) ([]models.Invitation, error) {
+	var remaining []models.Invitation
+	for _, invitation := range r.invitations {
+		if !r.deleted[invitation.InvitationId] {
+			remaining = append(remaining, invitation)
+		}
+	}
+	return remaining, nil
+}
+
+type fakeAuditRepository struct {
+	repositories.AuditRepository
+}
+
+func (r *fakeAuditRepository) InsertAuditEvent(ctx context.Context, tx *sql.Tx, event *models.AuditEvent) error {
+	return nil
+}
+
+type fakeOutboxRepository struct {
+	repositories.OutboxRepository
+	err error
+}
+
+func (r *fakeOutboxRepository) InsertEvent(ctx context.Context, tx *sql.Tx, event *models.OutboxEvent) error {
+	return r.err
+}
+
+type removeInviteeFixture struct {
+	service     AppointmentService
+	mock        sqlmock.Sqlmock
+	redis       *miniredis.Miniredis
+	outbox      *fakeOutboxRepository
+	invitations *fakeInvitationRepository
+}
+
+// newRemoveInviteeFixture sets up appointment 10 at version 3 hosted by user
+// 1, with user 2 invited as invitation 1 and user 3 as invitation 2.
+func newRemoveInviteeFixture(t *testing.T) *removeInviteeFixture {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Now().UTC()
+	appointment := &models.Appointment{AppointmentId: 10, HostId: 1, StartTime: now, EndTime: now.Add(time.Hour), Version: 3}
+	invitations := []models.Invitation{
+		{InvitationId: 1, AppointmentId: 10, InviteeId: 2, Status: models.InvitationStatusPending},
+		{InvitationId: 2, AppointmentId: 10, InviteeId: 3, Status: models.InvitationStatusAccepted},
+	}
+
+	outbox := &fakeOutboxRepository{}
+	invitationRepository := &fakeInvitationRepository{db: db, invitations: invitations, deleted: map[int]bool{}}
+	service := NewAppointmentService(
+		&fakeAppointmentRepository{appointment: appointment},
+		invitationRepository,
+		nil,
+		&fakeAuditRepository{},
+		nil,
+		outbox,
+		repositories.NewListCache(repositories.NewRedisRepository(client), time.Minute),
+	)
+
+	return &removeInviteeFixture{service: service, mock: mock, redis: mr, outbox: outbox, invitations: invitationRepository}
+}
+
+func (f *removeInviteeFixture) invalidated(userId int) bool {
+	return f.redis.Exists("cache:generation:" + strconv.Itoa(userId))
+}
+
+func TestRemoveInviteeInvalidatesAfterCommit(t *testing.T) {
+	f := newRemoveInviteeFixture(t)
+	f.mock.ExpectBegin()
+	f.mock.ExpectCommit()
+
+	version, err := f.service.RemoveInvitee(context.Background(), 1, 10, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 4 {
+		t.Errorf("appointment at version %d, want 4", version)
+	}
+	if err := f.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, userId := range []int{1, 2, 3} {
+		if !f.invalidated(userId) {
+			t.Errorf("lists of user %d not invalidated", userId)
+		}
+	}
+	if f.invalidated(4) {
+		t.Error("lists of an unrelated user invalidated")
+	}
+}
+
+func TestRemoveInviteeKeepsCacheOnRollback(t *testing.T) {
+	f := newRemoveInviteeFixture(t)
+	f.outbox.err = errors.New("outbox unavailable")
+	f.mock.ExpectBegin()
+	f.mock.ExpectRollback()
+
+	if _, err := f.service.RemoveInvitee(context.Background(), 1, 10, 0, 2); err == nil {
+		t.Fatal("expected the outbox error")
+	}
+	if err := f.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := f.redis.Keys(); len(keys) != 0 {
+		t.Fatalf("rolled back change invalidated %v", keys)
+	}
+}
+
+func TestRemoveInviteeKeepsCacheWhenCommitFails(t *testing.T) {
+	f := newRemoveInviteeFixture(t)
+	f.mock.ExpectBegin()
+	f.mock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+	if _, err := f.service.RemoveInvitee(context.Background(), 1, 10, 0, 2); err == nil {
+		t.Fatal("expected the commit error")
+	}
+	if err := f.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := f.redis.Keys(); len(keys) != 0 {
+		t.Fatalf("failed commit invalidated %v", keys)
+	}
+}
+
+func TestRemoveInviteeRefusesStaleVersion(t *testing.T) {
+	f := newRemoveInviteeFixture(t)
+	f.mock.ExpectBegin()
+	f.mock.ExpectRollback()
+
+	_, err := f.service.RemoveInvitee(context.Background(), 1, 10, 2, 2)
+	if !errors.Is(err, repositories.ErrAppointmentModified) {
+		t.Fatalf("got %v, want ErrAppointmentModified", err)
+	}
+	if err := f.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.invitations.deleted[1] {
+		t.Error("invitation deleted on a stale version")
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AuditService interface {
+	GetAppointmentHistory(ctx context.Context, userId int, appointmentId int) ([]models.AuditEvent, error)
+	SearchAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type auditService struct {
+	auditRepository       repositories.AuditRepository
+	appointmentRepository repositories.AppointmentRepository
+}
+
+func NewAuditService(auditRepository repositories.AuditRepository, appointmentRepository repositories.AppointmentRepository) AuditService {
+	return &auditService{
+		auditRepository:       auditRepository,
+		appointmentRepository: appointmentRepository,
+	}
+}
+
+// GetAppointmentHistory is only available to the host and the invitees.
+func (s *auditService) GetAppointmentHistory(ctx context.Context, userId int, appointmentId int) ([]models.AuditEvent, error) {
+	_, err := s.appointmentRepository.GetAppointmentForUser(ctx, userId, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.auditRepository.GetAuditEventsByAppointment(ctx, appointmentId)
+}
+
+func (s *auditService) SearchAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+
+	return s.auditRepository.SearchAuditEvents(ctx, filter)
+}
+
+// newAuditEvent builds an audit event keeping only the fields that differ
+// between the before and after snapshots. Either snapshot may be nil.
+func newAuditEvent(ctx context.Context, appointmentId int, entityType string, entityId int, action string, actorId *int, before, after map[string]interface{}) (*models.AuditEvent, error) {
+	changedBefore := make(map[string]interface{})
+	changedAfter := make(map[string]interface{})
+
+	for key, value := range before {
+		if other, ok := after[key]; !ok || !reflect.DeepEqual(value, other) {
+			changedBefore[key] = value
+		}
+	}
+	for key, value := range after {
+		if other, ok := before[key]; !ok || !reflect.DeepEqual(value, other) {
+			changedAfter[key] = value
+		}
+	}
+
+	beforeJSON, err := json.Marshal(changedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling audit before: %w", err)
+	}
+
+	afterJSON, err := json.Marshal(changedAfter)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling audit after: %w", err)
+	}
+
+	return &models.AuditEvent{
+		AppointmentId: appointmentId,
+		EntityType:    entityType,
+		EntityId:      entityId,
+		Action:        action,
+		ActorId:       actorId,
+		RequestId:     utils.RequestIdFromContext(ctx),
+		Before:        beforeJSON,
+		After:         afterJSON,
+		CreatedAt:     time.Now().UTC(),
+	}, nil
+}
+
+// recordAudit writes the audit event of a change in the change's transaction.
+func recordAudit(ctx context.Context, auditRepository repositories.AuditRepository, tx *sql.Tx, appointmentId int, entityType string, entityId int, action string, actorId *int, before, after map[string]interface{}) error {
+	event, err := newAuditEvent(ctx, appointmentId, entityType, entityId, action, actorId, before, after)
+	if err != nil {
+		return err
+	}
+
+	if err := auditRepository.InsertAuditEvent(ctx, tx, event); err != nil {
+		return fmt.Errorf("error record audit event: %w", err)
+	}
+
+	return nil
+}
+
+func appointmentSnapshot(appointment *models.Appointment) map[string]interface{} {
+	return map[string]interface{}{
+		"title":          appointment.Title,
+		"description":    appointment.Description,
+		"location":       appointment.Location,
+		"travel_minutes": appointment.TravelMinutes,
+		"start_time":     appointment.StartTime.UTC().Format(time.RFC3339),
+		"end_time":       appointment.EndTime.UTC().Format(time.RFC3339),
+		"quorum":         appointment.Quorum,
+		"is_open":        appointment.IsOpen,
+		"capacity":       appointment.Capacity,
+		"join_url":       appointment.JoinUrl,
+		"tags":           appointment.Tags,
+	}
+}
+
+func invitationSnapshot(invitation *models.Invitation) map[string]interface{} {
+	return map[string]interface{}{
+		"invitee_id": invitation.InviteeId,
+		"role":       invitation.Role,
+		"status":     invitation.Status,
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+)
+
+const defaultAutoReply = "out of office"
+
+const (
+	suggestionStep   = 30 * time.Minute
+	suggestionWindow = 7 * 24 * time.Hour
+	suggestionLimit  = 3
+)
+
+// AppointmentConflictError is returned when a required participant is busy
+// during the requested time. Suggestions holds free alternative slots.
+type AppointmentConflictError struct {
+	Conflicts   []models.AppointmentConflict
+	Suggestions []models.TimeSlot
+}
+
+func (e *AppointmentConflictError) Error() string {
+	return fmt.Sprintf("appointment conflict: %d conflicting appointment(s)", len(e.Conflicts))
+}
+
+func (e *AppointmentConflictError) AppError() *apperror.Error {
+	return apperror.Conflict("appointment conflicts with existing appointments of required participants").
+		WithDetails(map[string]interface{}{
+			"conflicts":   e.Conflicts,
+			"suggestions": e.Suggestions,
+		})
+}
+
+func overlaps(startA, endA, startB, endB time.Time) bool {
+	return startA.Before(endB) && startB.Before(endA)
+}
+
+// suggestSlots walks forward from `from` in fixed steps and returns up to
+// limit slots of the given duration that, widened by padding on both sides,
+// overlap none of the busy intervals.
+func suggestSlots(busy []models.BusyInterval, from, until time.Time, duration, padding, step time.Duration, limit int) []models.TimeSlot {
+	var slots []models.TimeSlot
+
+	for start := from; !start.Add(duration).After(until) && len(slots) < limit; start = start.Add(step) {
+		end := start.Add(duration)
+
+		if isFree(busy, start.Add(-padding), end.Add(padding)) {
+			slots = append(slots, models.TimeSlot{StartTime: start, EndTime: end})
+		}
+	}
+
+	return slots
+}
+
+func isFree(busy []models.BusyInterval, start, end time.Time) bool {
+	for _, b := range busy {
+		if collides(b, start, end) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// collides tells whether a candidate appointment of the interval's user hits
+// the busy interval once the candidate is widened by that user's buffers.
+func collides(b models.BusyInterval, start, end time.Time) bool {
+	before := time.Duration(b.BufferBeforeMinutes) * time.Minute
+	after := time.Duration(b.BufferAfterMinutes) * time.Minute
+
+	return overlaps(start.Add(-before), end.Add(after), b.StartTime, b.EndTime)
+}
+
+// bookableSlots lists the slots a booker can pick for the event type between
+// from and until. Availability windows are read in loc, the host timezone, and
+// a slot padded with the event buffers must not touch any busy interval.
+func bookableSlots(eventType models.EventType, loc *time.Location, busy []models.BusyInterval, booked []models.TimeSlot, from, until, now time.Time) []models.TimeSlot {
+	duration := time.Duration(eventType.DurationMinutes) * time.Minute
+	before := time.Duration(eventType.BufferBeforeMinutes) * time.Minute
+	after := time.Duration(eventType.BufferAfterMinutes) * time.Minute
+	earliest := now.Add(time.Duration(eventType.MinNoticeMinutes) * time.Minute)
+
+	perDay := make(map[string]int)
+	for _, b := range booked {
+		perDay[b.StartTime.In(loc).Format("2006-01-02")]++
+	}
+
+	var slots []models.TimeSlot
+
+	localFrom := from.In(loc)
+	for day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day(), 0, 0, 0, 0, loc); day.Before(until); day = day.AddDate(0, 0, 1) {
+		if eventType.MaxPerDay != nil && perDay[day.Format("2006-01-02")] >= *eventType.MaxPerDay {
+			continue
+		}
+
+		for _, window := range eventType.Availability {
+			if time.Weekday(window.Weekday) != day.Weekday() {
+				continue
+			}
+
+			windowStart, windowEnd, err := windowBounds(day, window)
+			if err != nil {
+				continue
+			}
+
+			for start := windowStart; !start.Add(duration).After(windowEnd); start = start.Add(duration) {
+				end := start.Add(duration)
+				if start.Before(earliest) || start.Before(from) || end.After(until) {
+					continue
+				}
+
+				if isFree(busy, start.Add(-before), end.Add(after)) {
+					slots = append(slots, models.TimeSlot{StartTime: start, EndTime: end})
+				}
+			}
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].StartTime.Before(slots[j].StartTime)
+	})
+
+	return slots
+}
+
+func windowBounds(day time.Time, window models.AvailabilityWindow) (time.Time, time.Time, error) {
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	end, err := time.Parse("15:04", window.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	loc := day.Location()
+	return time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc),
+		time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc), nil
+}
+
+// mergeBusy collapses busy intervals sorted by start time into disjoint slots.
+func mergeBusy(busy []models.BusyInterval) []models.TimeSlot {
+	var merged []models.TimeSlot
+
+	for _, b := range busy {
+		last := len(merged) - 1
+		if last >= 0 && !b.StartTime.After(merged[last].EndTime) {
+			if b.EndTime.After(merged[last].EndTime) {
+				merged[last].EndTime = b.EndTime
+			}
+			continue
+		}
+
+		merged = append(merged, models.TimeSlot{StartTime: b.StartTime, EndTime: b.EndTime})
+	}
+
+	return merged
+}
+
+// awayMessage is the auto-reply for an invitation received while away.
+func awayMessage(b models.BusyInterval) string {
+	if b.Kind == models.BusyKindHoliday {
+		return "holiday: " + b.Message
+	}
+	if b.Message == "" {
+		return defaultAutoReply
+	}
+
+	return b.Message
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+)
+
+// slotStarts formats the slot starts in loc as "Mon 15:04".
+func slotStarts(slots []models.TimeSlot, loc *time.Location) []string {
+	starts := make([]string, len(slots))
+	for i, slot := range slots {
+		starts[i] = slot.StartTime.In(loc).Format("Mon 15:04")
+	}
+	return starts
+}
+
+func equalStarts(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBookableSlots(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 March 2025 is a Monday
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2025, 3, day, hour, min, 0, 0, loc)
+	}
+	one := 1
+	monday := []models.AvailabilityWindow{{Weekday: int(time.Monday), Start: "09:00", End: "11:00"}}
+
+	tests := []struct {
+		name      string
+		eventType models.EventType
+		busy      []models.BusyInterval
+		booked    []models.TimeSlot
+		from      time.Time
+		now       time.Time
+		want      []string
+	}{
+		{
+			name:      "window split into back to back slots",
+			eventType: models.EventType{DurationMinutes: 30, Availability: monday},
+			from:      at(10, 0, 0),
+			now:       at(9, 12, 0),
+			want:      []string{"Mon 09:00", "Mon 09:30", "Mon 10:00", "Mon 10:30"},
+		},
+		{
+			name:      "slot not fitting the window end is dropped",
+			eventType: models.EventType{DurationMinutes: 45, Availability: monday},
+			from:      at(10, 0, 0),
+			now:       at(9, 12, 0),
+			want:      []string{"Mon 09:00", "Mon 09:45"},
+		},
+		{
+			name:      "busy interval removes the overlapping slot",
+			eventType: models.EventType{DurationMinutes: 30, Availability: monday},
+			busy:      []models.BusyInterval{{StartTime: at(10, 9, 30), EndTime: at(10, 10, 0)}},
+			from:      at(10, 0, 0),
+			now:       at(9, 12, 0),
+			want:      []string{"Mon 09:00", "Mon 10:00", "Mon 10:30"},
+		},
+		{
+			name:      "event buffers pad the slot",
+			eventType: models.EventType{DurationMinutes: 30, BufferBeforeMinutes: 15, Availability: monday},
+			busy:      []models.BusyInterval{{StartTime: at(10, 9, 30), EndTime: at(10, 10, 0)}},
+			from:      at(10, 0, 0),
+			now:       at(9, 12, 0),
+			want:      []string{"Mon 09:00", "Mon 10:30"},
+		},
+		{
+			name:      "host buffers pad the slot",
+			eventType: models.EventType{DurationMinutes: 30, Availability: monday},
+			busy:      []models.BusyInterval{{StartTime: at(10, 9, 30), EndTime: at(10, 10, 0), BufferAfterMinutes: 10}},
+			from:      at(10, 0, 0),
+			now:       at(9, 12, 0),
+			want:      []string{"Mon 10:00", "Mon 10:30"},
+		},
+		{
+			name:      "minimum notice",
+			eventType: models.EventType{DurationMinutes: 30, MinNoticeMinutes: 60, Availability: monday},
+			from:      at(10, 0, 0),
+			now:       at(10, 9, 10),
+			want:      []string{"Mon 10:30"},
+		},
+		{
+			name:      "range starting inside the window",
+			eventType: models.EventType{DurationMinutes: 30, Availability: monday},
+			from:      at(10, 10, 0),
+			now:       at(9, 12, 0),
+			want:      []string{"Mon 10:00", "Mon 10:30"},
+		},
+		{
+			name:      "day at its booking limit",
+			eventType: models.EventType{DurationMinutes: 30, MaxPerDay: &one, Availability: monday},
+			booked:    []models.TimeSlot{{StartTime: at(10, 9, 0), EndTime: at(10, 9, 30)}},
+			from:      at(10, 0, 0),
+			now:       at(9, 12, 0),
+			want:      []string{},
+		},
+		{
+			name: "other weekdays and invalid windows are skipped",
+			eventType: models.EventType{DurationMinutes: 60, Availability: []models.AvailabilityWindow{
+				{Weekday: int(time.Tuesday), Start: "09:00", End: "10:00"},
+				{Weekday: int(time.Monday), Start: "9am", End: "10am"},
+				{Weekday: int(time.Monday), Start: "13:00", End: "14:00"},
+			}},
+			from: at(10, 0, 0),
+			now:  at(9, 12, 0),
+			want: []string{"Mon 13:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the range is one local day given in UTC, as the handler receives it
+			from := tt.from.UTC()
+			until := from.Add(24 * time.Hour)
+
+			got := slotStarts(bookableSlots(tt.eventType, loc, tt.busy, tt.booked, from, until, tt.now), loc)
+			if !equalStarts(got, tt.want) {
+				t.Errorf("slots %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestSlots(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2025, 3, 10, hour, min, 0, 0, time.UTC)
+	}
+	busy := []models.BusyInterval{{StartTime: at(9, 0), EndTime: at(10, 0)}}
+
+	tests := []struct {
+		name    string
+		until   time.Time
+		padding time.Duration
+		limit   int
+		want    []string
+	}{
+		{"first free slots after the busy interval", at(12, 0), 0, 3, []string{"Mon 10:00", "Mon 10:30", "Mon 11:00"}},
+		{"padding keeps distance", at(12, 0), 15 * time.Minute, 3, []string{"Mon 10:30", "Mon 11:00", "Mon 11:30"}},
+		{"limit", at(12, 0), 0, 1, []string{"Mon 10:00"}},
+		{"slot must end before until", at(10, 45), 0, 3, []string{"Mon 10:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := suggestSlots(busy, at(9, 0), tt.until, 30*time.Minute, tt.padding, suggestionStep, tt.limit)
+			if got := slotStarts(slots, time.UTC); !equalStarts(got, tt.want) {
+				t.Errorf("suggestions %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeBusy(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2025, 3, 10, hour, 0, 0, 0, time.UTC)
+	}
+
+	merged := mergeBusy([]models.BusyInterval{
+		{StartTime: at(9), EndTime: at(11)},
+		{StartTime: at(10), EndTime: at(12)},
+		{StartTime: at(12), EndTime: at(13)},
+		{StartTime: at(12), EndTime: at(12)},
+		{StartTime: at(15), EndTime: at(16)},
+	})
+
+	want := []models.TimeSlot{{StartTime: at(9), EndTime: at(13)}, {StartTime: at(15), EndTime: at(16)}}
+	if len(merged) != len(want) {
+		t.Fatalf("merged %+v, want %+v", merged, want)
+	}
+	for i := range want {
+		if !merged[i].StartTime.Equal(want[i].StartTime) || !merged[i].EndTime.Equal(want[i].EndTime) {
+			t.Errorf("slot %d = %+v, want %+v", i, merged[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+)
+
+const maxSlotRange = 31 * 24 * time.Hour
+
+var (
+	ErrEventTypeNotFound = apperror.NotFound("event type not found")
+	ErrSlotUnavailable   = apperror.Conflict("requested slot is not available")
+	ErrSlotRangeTooLarge = apperror.Validation("slot range must not exceed 31 days")
+)
+
+type BookingService interface {
+	CreateBookingPage(ctx context.Context, page *models.BookingPage) (*models.BookingPage, error)
+	GetBookingPages(ctx context.Context, hostId int) ([]models.BookingPage, error)
+
+	GetSlots(ctx context.Context, slug string, eventTypeId int, startDate, endDate time.Time) ([]models.EventTypeSlots, error)
+	Book(ctx context.Context, slug string, eventTypeId int, startTime time.Time, guest *models.Guest) (*models.Appointment, error)
+}
+
+type bookingService struct {
+	bookingRepository     repositories.BookingRepository
+	appointmentRepository repositories.AppointmentRepository
+	auditRepository       repositories.AuditRepository
+	outboxRepository      repositories.OutboxRepository
+	listCache             repositories.ListCache
+}
+
+func NewBookingService(bookingRepository repositories.BookingRepository, appointmentRepository repositories.AppointmentRepository, auditRepository repositories.AuditRepository, outboxRepository repositories.OutboxRepository, listCache repositories.ListCache) BookingService {
+	return &bookingService{
+		bookingRepository:     bookingRepository,
+		appointmentRepository: appointmentRepository,
+		auditRepository:       auditRepository,
+		outboxRepository:      outboxRepository,
+		listCache:             listCache,
+	}
+}
+
+func (s *bookingService) CreateBookingPage(ctx context.Context, page *models.BookingPage) (_ *models.BookingPage, err error) {
+
+	tx, err := s.bookingRepository.BeginBookingTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error create booking page: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	page.IsActive = true
+	page.CreatedAt = time.Now().UTC()
+
+	createdPage, err := s.bookingRepository.InsertBookingPage(ctx, tx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range createdPage.EventTypes {
+		eventType := &createdPage.EventTypes[i]
+		eventType.BookingPageId = createdPage.BookingPageId
+		eventType.CreatedAt = createdPage.CreatedAt
+
+		_, err = s.bookingRepository.InsertEventType(ctx, tx, eventType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return createdPage, nil
+}
+
+func (s *bookingService) GetBookingPages(ctx context.Context, hostId int) ([]models.BookingPage, error) {
+	return s.bookingRepository.GetBookingPagesByHost(ctx, hostId)
+}
+
+// GetSlots computes free slots of a booking page. A zero eventTypeId returns
+// the slots of every event type on the page.
+func (s *bookingService) GetSlots(ctx context.Context, slug string, eventTypeId int, startDate, endDate time.Time) ([]models.EventTypeSlots, error) {
+	if endDate.Sub(startDate) > maxSlotRange {
+		return nil, ErrSlotRangeTooLarge
+	}
+
+	page, err := s.bookingRepository.GetBookingPageBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.EventTypeSlots
+	for _, eventType := range page.EventTypes {
+		if eventTypeId != 0 && eventType.EventTypeId != eventTypeId {
+			continue
+		}
+
+		slots, err := s.eventTypeSlots(ctx, nil, page, eventType, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, models.EventTypeSlots{
+			EventType: eventType,
+			Timezone:  page.Host.Timezone,
+			Slots:     slots,
+		})
+	}
+
+	if eventTypeId != 0 && len(result) == 0 {
+		return nil, ErrEventTypeNotFound
+	}
+
+	return result, nil
+}
+
+// eventTypeSlots computes the free slots of an event type. A booking passes
+// its transaction, holding the host schedule lock, to re-check the host's
+// busy time in it; listing slots passes nil.
+func (s *bookingService) eventTypeSlots(ctx context.Context, tx *sql.Tx, page *models.BookingPage, eventType models.EventType, startDate, endDate time.Time) ([]models.TimeSlot, error) {
+	loc, err := time.LoadLocation(page.Host.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("error load host timezone: %w", err)
+	}
+
+	// slots get padded by event and host buffers, a day of margin covers both
+	busyStart, busyEnd := startDate.Add(-24*time.Hour), endDate.Add(24*time.Hour)
+
+	var busy []models.BusyInterval
+	if tx != nil {
+		busy, err = s.appointmentRepository.GetBusyIntervalsTx(ctx, tx, []int{page.HostId}, busyStart, busyEnd)
+	} else {
+		busy, err = s.appointmentRepository.GetBusyIntervals(ctx, []int{page.HostId}, busyStart, busyEnd)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// max per day is counted on whole host days, so look at full days around the range
+	booked, err := s.bookingRepository.GetEventTypeBookings(ctx, eventType.EventTypeId, startDate.Add(-24*time.Hour), endDate.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	return bookableSlots(eventType, loc, busy, booked, startDate, endDate, time.Now().UTC()), nil
+}
+
+// Book creates an appointment on the host calendar with the external booker
+// as guest. The host schedule lock, also taken by appointments created in the
+// app, makes concurrent bookings of the same slot re-validate against each
+// other instead of double booking the host.
+func (s *bookingService) Book(ctx context.Context, slug string, eventTypeId int, startTime time.Time, guest *models.Guest) (_ *models.Appointment, err error) {
+	page, err := s.bookingRepository.GetBookingPageBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventType *models.EventType
+	for i := range page.EventTypes {
+		if page.EventTypes[i].EventTypeId == eventTypeId {
+			eventType = &page.EventTypes[i]
+		}
+	}
+	if eventType == nil {
+		return nil, ErrEventTypeNotFound
+	}
+
+	duration := time.Duration(eventType.DurationMinutes) * time.Minute
+
+	tx, err := s.bookingRepository.BeginBookingTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error book slot: %w", err)
+	}
+
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	err = s.appointmentRepository.LockSchedules(ctx, tx, []int{page.HostId})
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := s.eventTypeSlots(ctx, tx, page, *eventType, startTime, startTime.Add(duration))
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 || !slots[0].StartTime.Equal(startTime) {
+		err = ErrSlotUnavailable
+		return nil, err
+	}
+
+	appointment := &models.Appointment{
+		HostId:      page.HostId,
+		Title:       fmt.Sprintf("%s with %s", eventType.Title, guest.Name),
+		StartTime:   startTime.UTC(),
+		EndTime:     startTime.Add(duration).UTC(),
+		EventTypeId: &eventType.EventTypeId,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	createdAppointment, err := s.appointmentRepository.InsertAppointment(ctx, tx, appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	guest.AppointmentId = createdAppointment.AppointmentId
+	guest.CreatedAt = createdAppointment.CreatedAt
+
+	_, err = s.bookingRepository.InsertGuest(ctx, tx, guest)
+	if err != nil {
+		return nil, err
+	}
+	createdAppointment.Guests = []models.Guest{*guest}
+
+	after := appointmentSnapshot(createdAppointment)
+	after["guest"] = map[string]interface{}{"name": guest.Name, "email": guest.Email}
+
+	err = recordAudit(ctx, s.auditRepository, tx, createdAppointment.AppointmentId, models.AuditEntityAppointment, createdAppointment.AppointmentId,
+		models.AuditActionAppointmentCreated, nil, nil, after)
+	if err != nil {
+		return nil, err
+	}
+	err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventAppointmentCreated, createdAppointment.AppointmentId,
+		[]int{page.HostId}, createdAppointment)
+	if err != nil {
+		return nil, err
+	}
+
+	affected = []int{page.HostId}
+
+	return createdAppointment, nil
+}
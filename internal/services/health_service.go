@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/database"
+)
+
+const (
+	healthCheckTimeout = 2 * time.Second
+	workerLagThreshold = 5 * time.Minute
+)
+
+// HealthService probes the dependencies of the API for the readiness
+// endpoint and tracks whether the process is draining for shutdown.
+type HealthService interface {
+	Readiness(ctx context.Context) models.HealthReport
+	Drain()
+}
+
+type healthService struct {
+	db               *sql.DB
+	migrator         *database.Migrator
+	redisRepository  repositories.RedisRepository
+	outboxRepository repositories.OutboxRepository
+	jobRepository    repositories.JobRepository
+
+	draining atomic.Bool
+}
+
+func NewHealthService(
+	db *sql.DB,
+	migrator *database.Migrator,
+	redisRepository repositories.RedisRepository,
+	outboxRepository repositories.OutboxRepository,
+	jobRepository repositories.JobRepository,
+) HealthService {
+	return &healthService{
+		db:               db,
+		migrator:         migrator,
+		redisRepository:  redisRepository,
+		outboxRepository: outboxRepository,
+		jobRepository:    jobRepository,
+	}
+}
+
+// Drain marks the API as not ready, so load balancers stop sending new
+// requests before the server shuts down.
+func (s *healthService) Drain() {
+	s.draining.Store(true)
+}
+
+// Readiness runs every check concurrently, each bounded by its own timeout.
+// Worker lag can only degrade the report: API instances cannot fix it and
+// taking them out of rotation would not help.
+func (s *healthService) Readiness(ctx context.Context) models.HealthReport {
+	if s.draining.Load() {
+		return models.HealthReport{Status: models.HealthStatusDraining, Ready: false}
+	}
+
+	checks := map[string]func(ctx context.Context) models.HealthCheck{
+		"postgres":   s.checkPostgres,
+		"redis":      s.checkRedis,
+		"migrations": s.checkMigrations,
+		"worker":     s.checkWorkerLag,
+	}
+
+	report := models.HealthReport{
+		Status: models.HealthStatusUp,
+		Ready:  true,
+		Checks: make(map[string]models.HealthCheck, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) models.HealthCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			result := check(checkCtx)
+			result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		switch result.Status {
+		case models.HealthStatusDown:
+			report.Status = models.HealthStatusDown
+			report.Ready = false
+		case models.HealthStatusDegraded:
+			if report.Status == models.HealthStatusUp {
+				report.Status = models.HealthStatusDegraded
+			}
+		}
+	}
+
+	return report
+}
+
+func (s *healthService) checkPostgres(ctx context.Context) models.HealthCheck {
+	if err := s.db.PingContext(ctx); err != nil {
+		return models.HealthCheck{Status: models.HealthStatusDown, Error: err.Error()}
+	}
+
+	stats := s.db.Stats()
+	return models.HealthCheck{
+		Status: models.HealthStatusUp,
+		Details: map[string]interface{}{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+		},
+	}
+}
+
+func (s *healthService) checkRedis(ctx context.Context) models.HealthCheck {
+	if err := s.redisRepository.Ping(ctx); err != nil {
+		return models.HealthCheck{Status: models.HealthStatusDown, Error: err.Error()}
+	}
+
+	return models.HealthCheck{Status: models.HealthStatusUp}
+}
+
+// checkMigrations fails readiness when the schema was migrated away from the
+// version this build expects after the process started, e.g. a rollback.
+func (s *healthService) checkMigrations(ctx context.Context) models.HealthCheck {
+	current, dirty, err := s.migrator.Version()
+	if err != nil {
+		return models.HealthCheck{Status: models.HealthStatusDown, Error: err.Error()}
+	}
+
+	result := models.HealthCheck{
+		Status: models.HealthStatusUp,
+		Details: map[string]interface{}{
+			"version":  current,
+			"expected": s.migrator.Latest(),
+			"dirty":    dirty,
+		},
+	}
+
+	if dirty || current != s.migrator.Latest() {
+		result.Status = models.HealthStatusDown
+		result.Error = database.ErrSchemaVersion.Error()
+	}
+
+	return result
+}
+
+// checkWorkerLag reports how long the oldest outbox event and the oldest due
+// job have been waiting for a worker.
+func (s *healthService) checkWorkerLag(ctx context.Context) models.HealthCheck {
+	handlers := []string{OutboxHandlerRealtime, OutboxHandlerWebhooks}
+
+	oldestEvent, err := s.outboxRepository.OldestPending(ctx, handlers)
+	if err != nil {
+		return models.HealthCheck{Status: models.HealthStatusDegraded, Error: fmt.Sprintf("outbox lag: %v", err)}
+	}
+
+	oldestJob, err := s.jobRepository.OldestDue(ctx)
+	if err != nil {
+		return models.HealthCheck{Status: models.HealthStatusDegraded, Error: fmt.Sprintf("job lag: %v", err)}
+	}
+
+	outboxLag := lagSince(oldestEvent)
+	jobLag := lagSince(oldestJob)
+
+	result := models.HealthCheck{
+		Status: models.HealthStatusUp,
+		Details: map[string]interface{}{
+			"outbox_lag_seconds": outboxLag.Seconds(),
+			"job_lag_seconds":    jobLag.Seconds(),
+		},
+	}
+
+	if outboxLag > workerLagThreshold || jobLag > workerLagThreshold {
+		result.Status = models.HealthStatusDegraded
+		result.Error = fmt.Sprintf("worker is more than %s behind", workerLagThreshold)
+	}
+
+	return result
+}
+
+func lagSince(since *time.Time) time.Duration {
+	if since == nil {
+		return 0
+	}
+
+	lag := time.Since(*since)
+	if lag < 0 {
+		return 0
+	}
+
+	return lag
+}
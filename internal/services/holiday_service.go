@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+type HolidayService interface {
+	CreateHolidayCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error)
+	GetHolidayCalendars(ctx context.Context) ([]models.HolidayCalendar, error)
+	ImportHolidays(ctx context.Context, calendarId int, ics io.Reader) (int, error)
+	GetHolidays(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.Holiday, error)
+
+	AssignUsers(ctx context.Context, calendarId int, userIds []int) error
+	UnassignUser(ctx context.Context, calendarId int, userId int) error
+	GetCalendarUsers(ctx context.Context, calendarId int) ([]models.User, error)
+}
+
+type holidayService struct {
+	holidayRepository repositories.HolidayRepository
+}
+
+func NewHolidayService(holidayRepository repositories.HolidayRepository) HolidayService {
+	return &holidayService{
+		holidayRepository: holidayRepository,
+	}
+}
+
+func (s *holidayService) CreateHolidayCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error) {
+	calendar.CreatedAt = time.Now().UTC()
+
+	return s.holidayRepository.InsertHolidayCalendar(ctx, calendar)
+}
+
+func (s *holidayService) GetHolidayCalendars(ctx context.Context) ([]models.HolidayCalendar, error) {
+	return s.holidayRepository.GetHolidayCalendars(ctx)
+}
+
+// ImportHolidays loads the events of an .ics file into the calendar as whole
+// day holidays. Re-importing the same file updates entries by their UID.
+func (s *holidayService) ImportHolidays(ctx context.Context, calendarId int, ics io.Reader) (_ int, err error) {
+	if _, err := s.holidayRepository.GetHolidayCalendarById(ctx, calendarId); err != nil {
+		return 0, err
+	}
+
+	events, err := utils.ParseICSEvents(ics)
+	if err != nil {
+		return 0, &apperror.Error{Kind: apperror.KindValidation, Message: "invalid ics file", Details: err.Error(), Err: err}
+	}
+
+	tx, err := s.holidayRepository.BeginHolidayTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error import holidays: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	now := time.Now().UTC()
+	for _, event := range events {
+		startDate := truncateToDate(event.Start)
+		endDate := truncateToDate(event.End)
+		// timed events block every day they touch
+		endsMidDay := event.End.Hour() != 0 || event.End.Minute() != 0 || event.End.Second() != 0
+		if (!event.AllDay && endsMidDay) || !endDate.After(startDate) {
+			endDate = endDate.AddDate(0, 0, 1)
+		}
+
+		err = s.holidayRepository.UpsertHoliday(ctx, tx, &models.Holiday{
+			HolidayCalendarId: calendarId,
+			Uid:               event.Uid,
+			Name:              event.Summary,
+			StartDate:         startDate,
+			EndDate:           endDate,
+			CreatedAt:         now,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(events), nil
+}
+
+// GetHolidays returns the holidays the user observes, those of the calendars
+// they are assigned to.
+func (s *holidayService) GetHolidays(ctx context.Context, userId int, startDate, endDate time.Time) ([]models.Holiday, error) {
+	return s.holidayRepository.GetHolidays(ctx, userId, startDate, endDate)
+}
+
+// AssignUsers makes the holidays of the calendar block the users' time.
+func (s *holidayService) AssignUsers(ctx context.Context, calendarId int, userIds []int) error {
+	if _, err := s.holidayRepository.GetHolidayCalendarById(ctx, calendarId); err != nil {
+		return err
+	}
+
+	return s.holidayRepository.AssignHolidayCalendarUsers(ctx, calendarId, utils.UniqueInts(userIds))
+}
+
+func (s *holidayService) UnassignUser(ctx context.Context, calendarId int, userId int) error {
+	return s.holidayRepository.UnassignHolidayCalendarUser(ctx, calendarId, userId)
+}
+
+func (s *holidayService) GetCalendarUsers(ctx context.Context, calendarId int) ([]models.User, error) {
+	if _, err := s.holidayRepository.GetHolidayCalendarById(ctx, calendarId); err != nil {
+		return nil, err
+	}
+
+	return s.holidayRepository.GetHolidayCalendarUsers(ctx, calendarId)
+}
+
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// idempotencyTTL is how long a response is replayed for its key.
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyLockTTL frees the key of a request whose process died
+	// before it stored the response.
+	idempotencyLockTTL = 5 * time.Minute
+)
+
+var (
+	ErrIdempotencyInFlight = apperror.Conflict("a request with this idempotency key is still in progress")
+	ErrIdempotencyMismatch = apperror.Unprocessable("idempotency key was already used for a different request")
+)
+
+type IdempotencyService interface {
+	// Begin claims key for the request with the given fingerprint. It returns
+	// the record of an earlier request with the same key and fingerprint that
+	// completed, nil when the caller should handle the request.
+	Begin(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error)
+	// Complete stores the response to replay for key.
+	Complete(ctx context.Context, key string, record *models.IdempotencyRecord) error
+	// Release forgets key so a retry is handled again, e.g. after a failure.
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyService struct {
+	redisRepository repositories.RedisRepository
+}
+
+func NewIdempotencyService(redisRepository repositories.RedisRepository) IdempotencyService {
+	return &idempotencyService{
+		redisRepository: redisRepository,
+	}
+}
+
+func idempotencyKey(key string) string {
+	return "idempotency:" + key
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+	pending, err := json.Marshal(models.IdempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: %w", err)
+	}
+
+	claimed, err := s.redisRepository.SetNX(ctx, idempotencyKey(key), pending, idempotencyLockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: %w", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	stored, err := s.redisRepository.Get(ctx, idempotencyKey(key))
+	if errors.Is(err, redis.Nil) {
+		// released or expired since, the client may simply retry
+		return nil, ErrIdempotencyInFlight
+	}
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: %w", err)
+	}
+
+	var record models.IdempotencyRecord
+	if err := json.Unmarshal([]byte(stored), &record); err != nil {
+		return nil, fmt.Errorf("idempotency: %w", err)
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyMismatch
+	}
+	if !record.Completed {
+		return nil, ErrIdempotencyInFlight
+	}
+
+	return &record, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, key string, record *models.IdempotencyRecord) error {
+	record.Completed = true
+
+	stored, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+
+	if err := s.redisRepository.Set(ctx, idempotencyKey(key), stored, idempotencyTTL); err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+
+	return nil
+}
+
+func (s *idempotencyService) Release(ctx context.Context, key string) error {
+	if err := s.redisRepository.Delete(ctx, idempotencyKey(key)); err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+
+	return nil
+}
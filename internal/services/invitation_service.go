@@ -1,29 +1,214 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
 )
 
+var ErrInvitationWaitlisted = apperror.Conflict("invitation is waitlisted, it is accepted once a seat frees up")
+
 type InvitationService interface {
-	GetInvitations(userId int) ([]models.AppointmentInvitation, error)
-	UpdateStatusInvitation(userId int, invId int, status string) error
+	GetInvitations(ctx context.Context, userId int) ([]models.AppointmentInvitation, error)
+	// UpdateStatusInvitation is refused with ErrInvitationModified unless the
+	// invitation is still at version, 0 skips the check.
+	UpdateStatusInvitation(ctx context.Context, userId int, invId int, status string, version int) (*models.Invitation, error)
 }
 
 type invitationService struct {
-	invitationRepository repositories.InvitationRepository
+	invitationRepository  repositories.InvitationRepository
+	appointmentRepository repositories.AppointmentRepository
+	auditRepository       repositories.AuditRepository
+	outboxRepository      repositories.OutboxRepository
+	listCache             repositories.ListCache
 }
 
-func NewInvitationService(invitationRepository repositories.InvitationRepository) InvitationService {
+func NewInvitationService(invitationRepository repositories.InvitationRepository, appointmentRepository repositories.AppointmentRepository, auditRepository repositories.AuditRepository, outboxRepository repositories.OutboxRepository, listCache repositories.ListCache) InvitationService {
 	return &invitationService{
-		invitationRepository: invitationRepository,
+		invitationRepository:  invitationRepository,
+		appointmentRepository: appointmentRepository,
+		auditRepository:       auditRepository,
+		outboxRepository:      outboxRepository,
+		listCache:             listCache,
 	}
 }
 
-func (s *invitationService) GetInvitations(userId int) ([]models.AppointmentInvitation, error) {
-	return s.invitationRepository.GetInvitations(userId)
+func (s *invitationService) GetInvitations(ctx context.Context, userId int) ([]models.AppointmentInvitation, error) {
+	return s.invitationRepository.GetInvitations(ctx, userId)
 }
 
-func (s *invitationService) UpdateStatusInvitation(userId int, invId int, status string) error {
-	return s.invitationRepository.UpdateStatusInvitation(userId, invId, status)
+// UpdateStatusInvitation changes the RSVP of an invitation. On open appointments
+// an accept beyond capacity lands on the waitlist, and a seat given up by an
+// accepted attendee is handed to the first waitlisted user.
+func (s *invitationService) UpdateStatusInvitation(ctx context.Context, userId int, invId int, status string, version int) (_ *models.Invitation, err error) {
+
+	tx, err := s.invitationRepository.BeginInvitationTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error update invitation: %w", err)
+	}
+
+	// runs after the commit deferred below, so no reader caches the old rows
+	var affected []int
+	defer func() {
+		if err == nil {
+			s.listCache.Invalidate(ctx, affected...)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	invitation, err := s.invitationRepository.GetInvitationById(ctx, tx, userId, invId)
+	if err != nil {
+		return nil, err
+	}
+
+	// every status change on an appointment is serialized by its row lock
+	appointment, err := s.appointmentRepository.LockAppointment(ctx, tx, invitation.AppointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.CancelledAt != nil {
+		err = ErrAppointmentCancelled
+		return nil, err
+	}
+
+	invitation, err = s.invitationRepository.GetInvitationById(ctx, tx, userId, invId)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 && invitation.Version != version {
+		err = repositories.ErrInvitationModified.WithDetails(map[string]interface{}{"version": invitation.Version})
+		return nil, err
+	}
+
+	previous := invitation.Status
+	if previous == status {
+		return invitation, nil
+	}
+
+	if status == models.InvitationStatusAccepted {
+		if previous == models.InvitationStatusWaitlisted {
+			err = ErrInvitationWaitlisted
+			return nil, err
+		}
+
+		status, err = seatStatus(ctx, s.invitationRepository, tx, appointment)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before := invitationSnapshot(invitation)
+
+	err = s.invitationRepository.UpdateStatusInvitation(ctx, tx, invitation, status)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, s.auditRepository, tx, invitation.AppointmentId, models.AuditEntityInvitation, invitation.InvitationId,
+		models.AuditActionInvitationStatus, &userId, before, invitationSnapshot(invitation))
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordOutbox(ctx, s.outboxRepository, tx, invitationWebhookEvent(invitation.Status), appointment.AppointmentId,
+		[]int{appointment.HostId, userId}, invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.IsOpen && previous == models.InvitationStatusAccepted {
+		var promoted *models.Invitation
+		promoted, err = promoteWaitlisted(ctx, s.invitationRepository, s.auditRepository, tx, appointment)
+		if err != nil {
+			return nil, err
+		}
+		if promoted != nil {
+			err = recordOutbox(ctx, s.outboxRepository, tx, models.WebhookEventInvitationAccepted, appointment.AppointmentId,
+				[]int{appointment.HostId, promoted.InviteeId}, promoted)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	affected, err = appointmentUserIds(ctx, s.invitationRepository, tx, appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// promoteWaitlisted hands a free seat of the locked open appointment to the
+// longest waiting user and returns the promoted invitation, if any. The
+// promotion is audited as a system change.
+func promoteWaitlisted(ctx context.Context, invitationRepository repositories.InvitationRepository, auditRepository repositories.AuditRepository, tx *sql.Tx, appointment *models.Appointment) (*models.Invitation, error) {
+	status, err := seatStatus(ctx, invitationRepository, tx, appointment)
+	if err != nil || status != models.InvitationStatusAccepted {
+		return nil, err
+	}
+
+	next, err := invitationRepository.GetFirstWaitlisted(ctx, tx, appointment.AppointmentId)
+	if errors.Is(err, repositories.ErrInvitationNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	before := invitationSnapshot(next)
+
+	err = invitationRepository.UpdateStatusInvitation(ctx, tx, next, models.InvitationStatusAccepted)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, auditRepository, tx, appointment.AppointmentId, models.AuditEntityInvitation, next.InvitationId,
+		models.AuditActionInvitationStatus, nil, before, invitationSnapshot(next))
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// seatStatus tells whether a new attendee of the locked appointment gets a seat
+// or has to wait. Invite-only appointments are never capped.
+func seatStatus(ctx context.Context, invitationRepository repositories.InvitationRepository, tx *sql.Tx, appointment *models.Appointment) (string, error) {
+	if !appointment.IsOpen || appointment.Capacity == nil {
+		return models.InvitationStatusAccepted, nil
+	}
+
+	accepted, err := invitationRepository.CountInvitationsByStatus(ctx, tx, appointment.AppointmentId, models.InvitationStatusAccepted)
+	if err != nil {
+		return "", fmt.Errorf("error count seats: %w", err)
+	}
+
+	if accepted < *appointment.Capacity {
+		return models.InvitationStatusAccepted, nil
+	}
+
+	return models.InvitationStatusWaitlisted, nil
 }
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+const (
+	jobDefaultMaxAttempts = 5
+	jobVisibility         = 5 * time.Minute
+	jobIdleWait           = time.Second
+	jobBaseBackoff        = 10 * time.Second
+	jobMaxBackoff         = time.Hour
+)
+
+var ErrUnknownJobType = errors.New("no handler registered for job type")
+
+// JobHandler runs a job. Its context ends with the visibility timeout, after
+// which the job may be handed to another worker, or when the worker stops.
+type JobHandler func(ctx context.Context, job models.Job) error
+
+// JobService enqueues background jobs and runs them in worker processes.
+type JobService interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}, opts models.JobOptions) (*models.Job, error)
+
+	Register(jobType string, handler JobHandler)
+	Run(ctx context.Context, concurrency int)
+}
+
+type jobService struct {
+	jobRepository repositories.JobRepository
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+}
+
+func NewJobService(jobRepository repositories.JobRepository) JobService {
+	return &jobService{
+		jobRepository: jobRepository,
+		handlers:      make(map[string]JobHandler),
+	}
+}
+
+// Enqueue queues a job, run after opts.Delay. It returns
+// repositories.ErrDuplicateJob when the unique key is already queued.
+func (s *jobService) Enqueue(ctx context.Context, jobType string, payload interface{}, opts models.JobOptions) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling job payload: %w", err)
+	}
+
+	job := &models.Job{
+		JobId:       utils.NewRequestId(),
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: opts.MaxAttempts,
+		UniqueKey:   opts.UniqueKey,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = jobDefaultMaxAttempts
+	}
+
+	if err := s.jobRepository.Enqueue(ctx, job, time.Now().Add(opts.Delay)); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (s *jobService) Register(jobType string, handler JobHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[jobType] = handler
+}
+
+// Run works on jobs with the given number of goroutines until ctx is done.
+// Jobs still running then see their context end, those that fail because of
+// it are queued again right away. Run returns once they are settled.
+func (s *jobService) Run(ctx context.Context, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *jobService) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := s.jobRepository.Claim(ctx, jobVisibility)
+		if err != nil {
+			slog.ErrorContext(ctx, "claiming job", "error", err.Error())
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(jobIdleWait):
+			}
+			continue
+		}
+
+		s.process(ctx, job)
+	}
+}
+
+// process runs a claimed job and settles it. Settling does not use the worker
+// context so a shutdown does not leave the job in flight.
+func (s *jobService) process(ctx context.Context, job *models.Job) {
+	s.mu.RLock()
+	handler, ok := s.handlers[job.Type]
+	s.mu.RUnlock()
+
+	job.Attempts++
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type)
+	} else {
+		err = runJob(ctx, handler, *job)
+	}
+
+	settleCtx := context.WithoutCancel(ctx)
+
+	if err == nil {
+		if ackErr := s.jobRepository.Ack(settleCtx, job); ackErr != nil {
+			slog.ErrorContext(ctx, "acknowledging job", "job_id", job.JobId, "type", job.Type, "error", ackErr.Error())
+		}
+		return
+	}
+
+	// the worker is stopping: the attempt was cut short rather than failed,
+	// hand the job back now instead of after the visibility timeout
+	if ok && ctx.Err() != nil {
+		job.Attempts--
+		slog.InfoContext(ctx, "job interrupted", "job_id", job.JobId, "type", job.Type, "error", err.Error())
+		if retryErr := s.jobRepository.Retry(settleCtx, job, time.Now()); retryErr != nil {
+			slog.ErrorContext(ctx, "retrying job", "job_id", job.JobId, "type", job.Type, "error", retryErr.Error())
+		}
+		return
+	}
+
+	job.LastError = err.Error()
+
+	if job.Attempts >= job.MaxAttempts || !ok {
+		slog.ErrorContext(ctx, "job moved to dead-letter", "job_id", job.JobId, "type", job.Type, "attempts", job.Attempts,
+			"error", err.Error())
+		if buryErr := s.jobRepository.Bury(settleCtx, job); buryErr != nil {
+			slog.ErrorContext(ctx, "burying job", "job_id", job.JobId, "type", job.Type, "error", buryErr.Error())
+		}
+		return
+	}
+
+	slog.WarnContext(ctx, "job failed", "job_id", job.JobId, "type", job.Type, "attempts", job.Attempts, "error", err.Error())
+	if retryErr := s.jobRepository.Retry(settleCtx, job, time.Now().Add(jobBackoff(job.Attempts))); retryErr != nil {
+		slog.ErrorContext(ctx, "retrying job", "job_id", job.JobId, "type", job.Type, "error", retryErr.Error())
+	}
+}
+
+// runJob calls the handler, turning a panic into a failed attempt. The
+// handler's context ends with the worker's or after the visibility timeout.
+func runJob(ctx context.Context, handler JobHandler, job models.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, jobVisibility)
+	defer cancel()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// jobBackoff doubles the wait after every failed attempt, capped.
+func jobBackoff(attempts int) time.Duration {
+	backoff := jobBaseBackoff
+	for i := 1; i < attempts && backoff < jobMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > jobMaxBackoff {
+		return jobMaxBackoff
+	}
+	return backoff
+}
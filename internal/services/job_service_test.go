@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestJobService(t *testing.T) (*jobService, repositories.JobRepository) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	jobRepository := repositories.NewJobRepository(client)
+	return NewJobService(jobRepository).(*jobService), jobRepository
+}
+
+// claimQueued enqueues a job of the type and claims it like a worker.
+func claimQueued(t *testing.T, service *jobService, jobRepository repositories.JobRepository, jobType string) *models.Job {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := service.Enqueue(ctx, jobType, nil, models.JobOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := jobRepository.Claim(ctx, jobVisibility)
+	if err != nil || job == nil {
+		t.Fatalf("claimed %v, %v", job, err)
+	}
+	return job
+}
+
+func TestJobInterruptedByShutdownIsQueuedAgain(t *testing.T) {
+	service, jobRepository := newTestJobService(t)
+
+	started := make(chan struct{})
+	service.Register("test.slow", func(ctx context.Context, job models.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	job := claimQueued(t, service, jobRepository, "test.slow")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	service.process(ctx, job)
+
+	again, err := jobRepository.Claim(context.Background(), jobVisibility)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == nil || again.JobId != job.JobId {
+		t.Fatalf("claimed %+v, want job %s back right away", again, job.JobId)
+	}
+	if again.Attempts != 0 || again.LastError != "" {
+		t.Errorf("interrupted run counted as attempt %d: %q", again.Attempts, again.LastError)
+	}
+}
+
+func TestFailedJobWaitsForBackoff(t *testing.T) {
+	service, jobRepository := newTestJobService(t)
+	service.Register("test.failing", func(ctx context.Context, job models.Job) error {
+		return errors.New("downstream unavailable")
+	})
+
+	job := claimQueued(t, service, jobRepository, "test.failing")
+	service.process(context.Background(), job)
+
+	again, err := jobRepository.Claim(context.Background(), jobVisibility)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != nil {
+		t.Fatalf("failed job claimed again within %v", jobBaseBackoff)
+	}
+}
+
+func TestJobBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, jobBaseBackoff},
+		{1, jobBaseBackoff},
+		{2, 2 * jobBaseBackoff},
+		{3, 4 * jobBaseBackoff},
+		{9, 256 * jobBaseBackoff},
+		{10, jobMaxBackoff},
+		{50, jobMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := jobBackoff(tt.attempts); got != tt.want {
+			t.Errorf("jobBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
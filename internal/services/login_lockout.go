@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+)
+
+// lockoutMemory is how long earlier lockouts of a username count towards
+// doubling the next one.
+const lockoutMemory = 24 * time.Hour
+
+// LoginLockedError is returned while a username is locked after repeated
+// failed sign-ins, whether or not the password is right.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("sign-in locked for %s", e.RetryAfter)
+}
+
+func (e *LoginLockedError) AppError() *apperror.Error {
+	return apperror.RateLimited("too many failed sign-ins, try again later").
+		WithDetails(map[string]interface{}{
+			"retry_after": int(math.Ceil(e.RetryAfter.Seconds())),
+		})
+}
+
+// Keys are per username, also for unknown ones, so a lockout does not reveal
+// whether the user exists.
+func lockoutKey(kind string, username string) string {
+	return "login:" + kind + ":" + strings.ToLower(username)
+}
+
+// lockedFor returns how long the username stays locked, zero when it is not.
+func (s *userService) lockedFor(ctx context.Context, username string) (time.Duration, error) {
+	if s.lockout.Threshold <= 0 {
+		return 0, nil
+	}
+
+	ttl, err := s.redisRepository.TTL(ctx, lockoutKey("locked", username))
+	if err != nil {
+		return 0, fmt.Errorf("login lockout: %w", err)
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// recordFailure counts a failed sign-in and locks the username once the
+// threshold is reached, for twice as long as its previous lockout.
+func (s *userService) recordFailure(ctx context.Context, username string) error {
+	if s.lockout.Threshold <= 0 {
+		return nil
+	}
+
+	failures, err := s.redisRepository.Incr(ctx, lockoutKey("failures", username), s.lockout.Window)
+	if err != nil {
+		return fmt.Errorf("login lockout: %w", err)
+	}
+	if failures < int64(s.lockout.Threshold) {
+		return nil
+	}
+
+	lockouts, err := s.redisRepository.Incr(ctx, lockoutKey("lockouts", username), lockoutMemory)
+	if err != nil {
+		return fmt.Errorf("login lockout: %w", err)
+	}
+
+	duration := s.lockout.Duration
+	for i := int64(1); i < lockouts && duration < s.lockout.MaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > s.lockout.MaxDuration {
+		duration = s.lockout.MaxDuration
+	}
+
+	if err := s.redisRepository.Set(ctx, lockoutKey("locked", username), 1, duration); err != nil {
+		return fmt.Errorf("login lockout: %w", err)
+	}
+	if err := s.redisRepository.Delete(ctx, lockoutKey("failures", username)); err != nil {
+		return fmt.Errorf("login lockout: %w", err)
+	}
+
+	return nil
+}
+
+// failSignIn records the failure and returns reason, the lockout it may have
+// started applies from the next attempt.
+func (s *userService) failSignIn(ctx context.Context, username string, reason error) error {
+	if err := s.recordFailure(ctx, username); err != nil {
+		return err
+	}
+
+	return reason
+}
+
+// clearFailures forgets the failed sign-ins of a username after it signed in.
+func (s *userService) clearFailures(ctx context.Context, username string) error {
+	if s.lockout.Threshold <= 0 {
+		return nil
+	}
+
+	if err := s.redisRepository.Delete(ctx, lockoutKey("failures", username)); err != nil {
+		return fmt.Errorf("login lockout: %w", err)
+	}
+	if err := s.redisRepository.Delete(ctx, lockoutKey("lockouts", username)); err != nil {
+		return fmt.Errorf("login lockout: %w", err)
+	}
+
+	return nil
+}
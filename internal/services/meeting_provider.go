@@ -0,0 +1,90 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+)
+
+const (
+	MeetingProviderStatic = "static"
+	MeetingProviderFake   = "fake"
+)
+
+// MeetingProvider generates the join link of a virtual meeting for an appointment.
+type MeetingProvider interface {
+	Name() string
+	CreateMeeting(appointment *models.Appointment) (string, error)
+}
+
+// NewMeetingProvider picks the provider configured by name. An empty name
+// disables video meetings and returns a nil provider.
+func NewMeetingProvider(name, urlTemplate string) (MeetingProvider, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case MeetingProviderStatic:
+		return NewStaticMeetingProvider(urlTemplate)
+	case MeetingProviderFake:
+		return &FakeMeetingProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown meeting provider %q", name)
+	}
+}
+
+// StaticMeetingProvider fills a URL template, e.g. a personal meeting room
+// "https://meet.example.com/{appointment_id}-{token}". {token} is a random
+// value so links of different appointments cannot be guessed.
+type StaticMeetingProvider struct {
+	template string
+}
+
+func NewStaticMeetingProvider(template string) (*StaticMeetingProvider, error) {
+	if !strings.HasPrefix(template, "https://") && !strings.HasPrefix(template, "http://") {
+		return nil, fmt.Errorf("meeting url template must be an http(s) url")
+	}
+
+	return &StaticMeetingProvider{template: template}, nil
+}
+
+func (p *StaticMeetingProvider) Name() string {
+	return MeetingProviderStatic
+}
+
+func (p *StaticMeetingProvider) CreateMeeting(appointment *models.Appointment) (string, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("error generate meeting token: %w", err)
+	}
+
+	return strings.NewReplacer(
+		"{appointment_id}", strconv.Itoa(appointment.AppointmentId),
+		"{host_id}", strconv.Itoa(appointment.HostId),
+		"{token}", hex.EncodeToString(token),
+	).Replace(p.template), nil
+}
+
+// FakeMeetingProvider returns predictable links and records the appointments
+// it was called for. Err makes CreateMeeting fail.
+type FakeMeetingProvider struct {
+	Err          error
+	Appointments []int
+}
+
+func (p *FakeMeetingProvider) Name() string {
+	return MeetingProviderFake
+}
+
+func (p *FakeMeetingProvider) CreateMeeting(appointment *models.Appointment) (string, error) {
+	if p.Err != nil {
+		return "", p.Err
+	}
+
+	p.Appointments = append(p.Appointments, appointment.AppointmentId)
+
+	return fmt.Sprintf("https://meet.invalid/%d", appointment.AppointmentId), nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 50
+	// failures of a handler at one event before it is dead-lettered, with
+	// backoff they span about a quarter of an hour
+	outboxMaxAttempts = 10
+)
+
+// Names of the outbox handlers run by the worker.
+const (
+	OutboxHandlerRealtime = "realtime"
+	OutboxHandlerWebhooks = "webhooks"
+)
+
+// OutboxHandler reacts to outbox events. Handle runs inside the relay
+// transaction that marks the event as processed by the handler, so writes
+// made through tx happen exactly once. Effects outside the database happen
+// again if the relay dies before committing and should be idempotent.
+type OutboxHandler interface {
+	Name() string
+	Handle(ctx context.Context, tx *sql.Tx, event models.OutboxEvent) error
+}
+
+// OutboxRelay hands committed outbox events to every registered handler.
+type OutboxRelay interface {
+	Register(handler OutboxHandler)
+	Run(ctx context.Context)
+}
+
+type outboxRelay struct {
+	outboxRepository repositories.OutboxRepository
+	handlers         []OutboxHandler
+}
+
+func NewOutboxRelay(outboxRepository repositories.OutboxRepository) OutboxRelay {
+	return &outboxRelay{
+		outboxRepository: outboxRepository,
+	}
+}
+
+// Register adds a handler. Handlers must be registered before Run.
+func (r *outboxRelay) Register(handler OutboxHandler) {
+	r.handlers = append(r.handlers, handler)
+}
+
+// Run relays events until ctx is done. Every handler has its own loop so a
+// slow or failing handler does not hold up the others.
+func (r *outboxRelay) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, handler := range r.handlers {
+		wg.Add(1)
+		go func(handler OutboxHandler) {
+			defer wg.Done()
+			r.runHandler(ctx, handler)
+		}(handler)
+	}
+	wg.Wait()
+}
+
+func (r *outboxRelay) runHandler(ctx context.Context, handler OutboxHandler) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			claimed, err := r.relayBatch(ctx, handler)
+			if err != nil {
+				slog.ErrorContext(ctx, "outbox relay failed", "handler", handler.Name(), "error", err.Error())
+				break
+			}
+
+			if claimed < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// relayBatch claims a batch of events and hands them to the handler in order.
+// Each event runs under a savepoint, a failing event is rolled back alone and
+// retried with backoff, until it fails outboxMaxAttempts times and is
+// dead-lettered. Until then the later events of its appointment wait, those
+// of other appointments go on.
+func (r *outboxRelay) relayBatch(ctx context.Context, handler OutboxHandler) (claimed int, err error) {
+	tx, err := r.outboxRepository.BeginOutboxTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "recovered from panic", "panic", p)
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				err = commitErr
+			}
+		}
+	}()
+
+	events, err := r.outboxRepository.ClaimEvents(ctx, tx, handler.Name(), outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := map[int]bool{}
+
+	for _, event := range events {
+		if failed[event.AppointmentId] {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `SAVEPOINT outbox_event`)
+		if err != nil {
+			return 0, err
+		}
+
+		handleErr := handler.Handle(ctx, tx, event)
+		if handleErr == nil {
+			handleErr = r.outboxRepository.MarkProcessed(ctx, tx, event.OutboxEventId, handler.Name())
+		}
+
+		if handleErr != nil {
+			slog.WarnContext(ctx, "outbox event failed", "handler", handler.Name(), "event_id", event.OutboxEventId,
+				"type", event.EventType, "error", handleErr.Error())
+
+			_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT outbox_event`)
+			if err != nil {
+				return 0, err
+			}
+
+			var deadLettered bool
+			deadLettered, err = r.outboxRepository.RecordFailure(ctx, tx, event.OutboxEventId, handler.Name(), handleErr.Error(), outboxMaxAttempts)
+			if err != nil {
+				return 0, err
+			}
+			if !deadLettered {
+				failed[event.AppointmentId] = true
+				continue
+			}
+
+			slog.ErrorContext(ctx, "outbox event dead-lettered", "handler", handler.Name(), "event_id", event.OutboxEventId,
+				"type", event.EventType, "attempts", outboxMaxAttempts)
+		}
+	}
+
+	return len(events), nil
+}
+
+// recordOutbox queues an event in tx. Recipients are the users the event is
+// pushed to, duplicates are removed.
+func recordOutbox(ctx context.Context, outboxRepository repositories.OutboxRepository, tx *sql.Tx, eventType string, appointmentId int, recipients []int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error marshalling outbox payload: %w", err)
+	}
+
+	event := &models.OutboxEvent{
+		EventType:     eventType,
+		AppointmentId: appointmentId,
+		Recipients:    utils.UniqueInts(recipients),
+		Payload:       payload,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	if err := outboxRepository.InsertEvent(ctx, tx, event); err != nil {
+		return fmt.Errorf("error record outbox event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+)
+
+type relayOutboxRepository struct {
+	repositories.OutboxRepository
+	db        *sql.DB
+	events    []models.OutboxEvent
+	processed []int64
+	failed    []int64
+}
+
+func (r *relayOutboxRepository) BeginOutboxTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *relayOutboxRepository) ClaimEvents(ctx context.Context, tx *sql.Tx, handler string, limit int) ([]models.OutboxEvent, error) {
+	return r.events, nil
+}
+
+func (r *relayOutboxRepository) MarkProcessed(ctx context.Context, tx *sql.Tx, eventId int64, handler string) error {
+	r.processed = append(r.processed, eventId)
+	return nil
+}
+
+func (r *relayOutboxRepository) RecordFailure(ctx context.Context, tx *sql.Tx, eventId int64, handler string, reason string, maxAttempts int) (bool, error) {
+	r.failed = append(r.failed, eventId)
+	return false, nil
+}
+
+// failingHandler fails the events in fail.
+type failingHandler struct {
+	fail map[int64]bool
+}
+
+func (h *failingHandler) Name() string { return "test" }
+
+func (h *failingHandler) Handle(ctx context.Context, tx *sql.Tx, event models.OutboxEvent) error {
+	if h.fail[event.OutboxEventId] {
+		return errors.New("handler failed")
+	}
+	return nil
+}
+
+func TestRelayBatchHoldsBackOnlyTheFailedAppointment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// events 1 and 3 belong to appointment 10, 2 and 4 to appointment 20
+	repo := &relayOutboxRepository{db: db, events: []models.OutboxEvent{
+		{OutboxEventId: 1, AppointmentId: 10},
+		{OutboxEventId: 2, AppointmentId: 20},
+		{OutboxEventId: 3, AppointmentId: 10},
+		{OutboxEventId: 4, AppointmentId: 20},
+	}}
+	relay := &outboxRelay{outboxRepository: repo}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT outbox_event").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT outbox_event").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT outbox_event").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT outbox_event").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	claimed, err := relay.relayBatch(context.Background(), &failingHandler{fail: map[int64]bool{1: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed != 4 {
+		t.Errorf("claimed %d, want 4", claimed)
+	}
+	if len(repo.failed) != 1 || repo.failed[0] != 1 {
+		t.Errorf("failures recorded for %v, want [1]", repo.failed)
+	}
+	if len(repo.processed) != 2 || repo.processed[0] != 2 || repo.processed[1] != 4 {
+		t.Errorf("processed %v, want [2 4]", repo.processed)
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+)
+
+type RateLimitService interface {
+	// Allow counts a request of client, e.g. "ip:10.0.0.1", under the named
+	// policy. Windows live in Redis so every replica shares them.
+	Allow(ctx context.Context, name string, client string, policy models.RateLimitPolicy) (*models.RateLimit, error)
+}
+
+type rateLimitService struct {
+	redisRepository repositories.RedisRepository
+}
+
+func NewRateLimitService(redisRepository repositories.RedisRepository) RateLimitService {
+	return &rateLimitService{
+		redisRepository: redisRepository,
+	}
+}
+
+func (s *rateLimitService) Allow(ctx context.Context, name string, client string, policy models.RateLimitPolicy) (*models.RateLimit, error) {
+	return s.redisRepository.SlidingWindow(ctx, "ratelimit:"+name+":"+client, policy)
+}
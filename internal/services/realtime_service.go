@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+)
+
+const (
+	realtimeChannel    = "realtime:events"
+	realtimeBufferSize = 32
+)
+
+// RealtimeService fans events out to the streams of connected users. Events go
+// through Redis pub/sub so every replica delivers to the users connected to it.
+type RealtimeService interface {
+	Publish(ctx context.Context, recipients []int, eventType string, appointmentId int, data interface{}) error
+	Subscribe(userId int) (<-chan models.RealtimeEvent, func())
+	Run(ctx context.Context)
+}
+
+// realtimeMessage is what travels between replicas. Recipients never leave the
+// server, a client only learns about events addressed to it.
+type realtimeMessage struct {
+	Recipients []int                `json:"recipients"`
+	Event      models.RealtimeEvent `json:"event"`
+}
+
+type realtimeService struct {
+	redisRepository repositories.RedisRepository
+
+	mu          sync.RWMutex
+	subscribers map[int]map[chan models.RealtimeEvent]struct{}
+}
+
+func NewRealtimeService(redisRepository repositories.RedisRepository) RealtimeService {
+	return &realtimeService{
+		redisRepository: redisRepository,
+		subscribers:     make(map[int]map[chan models.RealtimeEvent]struct{}),
+	}
+}
+
+func (s *realtimeService) Publish(ctx context.Context, recipients []int, eventType string, appointmentId int, data interface{}) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error marshalling realtime data: %w", err)
+	}
+
+	message, err := json.Marshal(realtimeMessage{
+		Recipients: recipients,
+		Event: models.RealtimeEvent{
+			Type:          eventType,
+			AppointmentId: appointmentId,
+			Data:          payload,
+			CreatedAt:     time.Now().UTC(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling realtime event: %w", err)
+	}
+
+	return s.redisRepository.Publish(ctx, realtimeChannel, message)
+}
+
+// Subscribe registers a stream of the user. The returned function must be
+// called once the stream is closed.
+func (s *realtimeService) Subscribe(userId int) (<-chan models.RealtimeEvent, func()) {
+	ch := make(chan models.RealtimeEvent, realtimeBufferSize)
+
+	s.mu.Lock()
+	if s.subscribers[userId] == nil {
+		s.subscribers[userId] = make(map[chan models.RealtimeEvent]struct{})
+	}
+	s.subscribers[userId][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers[userId], ch)
+		if len(s.subscribers[userId]) == 0 {
+			delete(s.subscribers, userId)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Run relays events from Redis to local subscribers until ctx is done.
+func (s *realtimeService) Run(ctx context.Context) {
+	pubsub := s.redisRepository.Subscribe(ctx, realtimeChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var message realtimeMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+				slog.WarnContext(ctx, "invalid realtime message", "error", err.Error())
+				continue
+			}
+
+			s.dispatch(ctx, message)
+		}
+	}
+}
+
+func (s *realtimeService) dispatch(ctx context.Context, message realtimeMessage) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, userId := range message.Recipients {
+		for ch := range s.subscribers[userId] {
+			select {
+			case ch <- message.Event:
+			default:
+				// a stalled stream must not hold up everybody else
+				slog.WarnContext(ctx, "realtime event dropped", "type", message.Event.Type, "user_id", userId)
+			}
+		}
+	}
+}
+
+// realtimeOutboxHandler pushes outbox events that have recipients to their
+// connected clients.
+type realtimeOutboxHandler struct {
+	realtimeService RealtimeService
+}
+
+func NewRealtimeOutboxHandler(realtimeService RealtimeService) OutboxHandler {
+	return &realtimeOutboxHandler{realtimeService: realtimeService}
+}
+
+func (h *realtimeOutboxHandler) Name() string {
+	return OutboxHandlerRealtime
+}
+
+func (h *realtimeOutboxHandler) Handle(ctx context.Context, tx *sql.Tx, event models.OutboxEvent) error {
+	if len(event.Recipients) == 0 {
+		return nil
+	}
+
+	return h.realtimeService.Publish(ctx, event.Recipients, realtimeEventType(event.EventType), event.AppointmentId, event.Payload)
+}
+
+// realtimeEventType maps an outbox event type to the type clients see. RSVP
+// changes are all pushed as invitation.updated.
+func realtimeEventType(eventType string) string {
+	switch eventType {
+	case models.WebhookEventInvitationCreated, models.WebhookEventInvitationAccepted,
+		models.WebhookEventInvitationRejected, models.WebhookEventInvitationWaitlisted:
+		return models.RealtimeInvitationUpdated
+	default:
+		return eventType
+	}
+}
+
+// participantIds lists the host and the invitees of an appointment, leaving
+// out invitees who declined when activeOnly is set.
+func participantIds(hostId int, invitations []models.Invitation, activeOnly bool) []int {
+	ids := []int{hostId}
+	for _, inv := range invitations {
+		if activeOnly && inv.Status == models.InvitationStatusRejected {
+			continue
+		}
+		ids = append(ids, inv.InviteeId)
+	}
+	return ids
+}
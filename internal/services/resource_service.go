@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+)
+
+type ResourceService interface {
+	GetResources(ctx context.Context) ([]models.Resource, error)
+	CreateResource(ctx context.Context, resource *models.Resource) (*models.Resource, error)
+	GetResourceBusy(ctx context.Context, resourceId int, startDate, endDate time.Time) ([]models.TimeSlot, error)
+	GetResourceCalendar(ctx context.Context, resourceId int, startDate, endDate time.Time) (*models.Resource, []models.ResourceBooking, error)
+}
+
+type resourceService struct {
+	resourceRepository repositories.ResourceRepository
+}
+
+func NewResourceService(resourceRepository repositories.ResourceRepository) ResourceService {
+	return &resourceService{
+		resourceRepository: resourceRepository,
+	}
+}
+
+func (s *resourceService) GetResources(ctx context.Context) ([]models.Resource, error) {
+	return s.resourceRepository.GetResources(ctx)
+}
+
+func (s *resourceService) CreateResource(ctx context.Context, resource *models.Resource) (*models.Resource, error) {
+	resource.CreatedAt = time.Now().UTC()
+
+	return s.resourceRepository.InsertResource(ctx, resource)
+}
+
+func (s *resourceService) GetResourceBusy(ctx context.Context, resourceId int, startDate, endDate time.Time) ([]models.TimeSlot, error) {
+	if _, err := s.resourceRepository.GetResourceById(ctx, resourceId); err != nil {
+		return nil, err
+	}
+
+	return s.resourceRepository.GetResourceBusy(ctx, resourceId, startDate, endDate)
+}
+
+// GetResourceCalendar lists the bookings of a resource in the resource's own timezone.
+func (s *resourceService) GetResourceCalendar(ctx context.Context, resourceId int, startDate, endDate time.Time) (*models.Resource, []models.ResourceBooking, error) {
+	resource, err := s.resourceRepository.GetResourceById(ctx, resourceId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bookings, err := s.resourceRepository.GetResourceBookings(ctx, resourceId, startDate, endDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := time.LoadLocation(resource.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error load resource timezone: %w", err)
+	}
+
+	for i := range bookings {
+		bookings[i].StartTime = bookings[i].StartTime.In(loc)
+		bookings[i].EndTime = bookings[i].EndTime.In(loc)
+	}
+
+	return resource, bookings, nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+)
+
+type TimeOffService interface {
+	CreateTimeOff(ctx context.Context, timeOff *models.TimeOff) (*models.TimeOff, error)
+	GetTimeOff(ctx context.Context, userId int) ([]models.TimeOff, error)
+	DeleteTimeOff(ctx context.Context, userId int, timeOffId int) error
+}
+
+type timeOffService struct {
+	timeOffRepository repositories.TimeOffRepository
+}
+
+func NewTimeOffService(timeOffRepository repositories.TimeOffRepository) TimeOffService {
+	return &timeOffService{
+		timeOffRepository: timeOffRepository,
+	}
+}
+
+func (s *timeOffService) CreateTimeOff(ctx context.Context, timeOff *models.TimeOff) (*models.TimeOff, error) {
+	timeOff.CreatedAt = time.Now().UTC()
+
+	return s.timeOffRepository.InsertTimeOff(ctx, timeOff)
+}
+
+// GetTimeOff lists current and upcoming time off of the user.
+func (s *timeOffService) GetTimeOff(ctx context.Context, userId int) ([]models.TimeOff, error) {
+	return s.timeOffRepository.GetTimeOffByUserId(ctx, userId, time.Now().UTC())
+}
+
+func (s *timeOffService) DeleteTimeOff(ctx context.Context, userId int, timeOffId int) error {
+	return s.timeOffRepository.DeleteTimeOff(ctx, userId, timeOffId)
+}
@@ -3,47 +3,71 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
 	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
 )
 
+var (
+	ErrInvalidCredentials = apperror.Unauthorized("invalid username or password")
+	ErrInvalidRole        = apperror.Validation("role must be admin or staff")
+	ErrInvalidTimezone    = apperror.Validation("unknown timezone")
+	ErrPasswordTooShort   = apperror.Validation("password must be at least 8 characters")
+	ErrRefreshTokenReused = apperror.Unauthorized("refresh token was already used")
+)
+
+// refreshReuseGrace is how long after a rotation the previous refresh token
+// is still answered, with the tokens it was rotated to. Clients that refresh
+// from two tabs or retry a refresh whose response was lost land here.
+const refreshReuseGrace = 30 * time.Second
+
 type UserService interface {
-	Authenticate(email string) (*models.User, *models.JwtToken, error)
-	RefreshToken(refreshToken string, sessionId string) (*models.User, *models.JwtToken, error)
-	GetUsers() ([]models.User, error)
-	UpdateUserTimezone(userId int, timezone string) error
+	Authenticate(ctx context.Context, username string, password string) (*models.User, *models.JwtToken, error)
+	RefreshToken(ctx context.Context, refreshToken string, sessionId string) (*models.User, *models.JwtToken, error)
+	GetUsers(ctx context.Context) ([]models.User, error)
+	UpdateUserTimezone(ctx context.Context, userId int, timezone string) error
+	UpdateUserBuffers(ctx context.Context, userId int, bufferBefore int, bufferAfter int) error
+
+	CreateUser(ctx context.Context, user *models.User, password string) (*models.User, error)
+	ResetPassword(ctx context.Context, username string, password string) error
+	DisableUser(ctx context.Context, username string) error
 }
 
 type userService struct {
 	userRepository  repositories.UserRepository
 	redisRepository repositories.RedisRepository
+	tokenIssuer     *utils.TokenIssuer
+	lockout         models.LockoutPolicy
+	listCache       repositories.ListCache
 }
 
-func NewUserService(userRepository repositories.UserRepository, redisRepository repositories.RedisRepository) UserService {
+func NewUserService(userRepository repositories.UserRepository, redisRepository repositories.RedisRepository, tokenIssuer *utils.TokenIssuer, lockout models.LockoutPolicy, listCache repositories.ListCache) UserService {
 	return &userService{
 		userRepository:  userRepository,
 		redisRepository: redisRepository,
+		tokenIssuer:     tokenIssuer,
+		lockout:         lockout,
+		listCache:       listCache,
 	}
 }
 
-func (s *userService) GetUsers() ([]models.User, error) {
-	return s.userRepository.GetUsers()
+func (s *userService) GetUsers(ctx context.Context) ([]models.User, error) {
+	return s.userRepository.GetUsers(ctx)
 }
 
-func (s *userService) RefreshToken(refreshToken string, sessionId string) (*models.User, *models.JwtToken, error) {
+func (s *userService) RefreshToken(ctx context.Context, refreshToken string, sessionId string) (*models.User, *models.JwtToken, error) {
 
-	_, err := utils.VerifyToken(refreshToken, true)
+	_, err := s.tokenIssuer.VerifyToken(refreshToken, true)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	dataJSON, err := s.redisRepository.Get(context.Background(), sessionId)
+	dataJSON, err := s.redisRepository.Get(ctx, sessionId)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -54,33 +78,44 @@ func (s *userService) RefreshToken(refreshToken string, sessionId string) (*mode
 	}
 
 	userId := dataSession["user_id"].(float64)
-	user, err := s.userRepository.GetUserById(int(userId))
+	user, err := s.userRepository.GetUserById(ctx, int(userId))
 	if err != nil {
 		return nil, nil, fmt.Errorf("refresh token: failed to get user data - %w", err)
 	}
 
-	newToken, err := utils.GenerateSessionToken(sessionId)
+	// every refresh rotates the token. The one rotated away just before is
+	// answered for a short while, anything else is a reuse: it is refused,
+	// the session itself stays valid for whoever holds the current token
+	if current, _ := dataSession["refresh_token"].(string); current != refreshToken {
+		previous, _ := dataSession["previous_refresh_token"].(string)
+		rotatedAt, _ := dataSession["rotated_at"].(float64)
+		if previous != refreshToken || time.Since(time.Unix(int64(rotatedAt), 0)) > refreshReuseGrace {
+			return nil, nil, ErrRefreshTokenReused
+		}
+
+		accessToken, _ := dataSession["access_token"].(string)
+		return user, &models.JwtToken{AccessToken: accessToken, RefreshToken: current}, nil
+	}
+
+	newToken, err := s.tokenIssuer.GenerateSessionToken(sessionId)
 	if err != nil {
 		return nil, nil, fmt.Errorf("refresh token: failed to generate new session - %w", err)
 	}
 
 	dataUser, err := json.Marshal(map[string]interface{}{
-		"user_id":       user.UserId,
-		"timezone":      user.Timezone,
-		"role":          user.Role,
-		"access_token":  newToken.AccessToken,
-		"refresh_token": newToken.RefreshToken,
+		"user_id":                user.UserId,
+		"timezone":               user.Timezone,
+		"role":                   user.Role,
+		"access_token":           newToken.AccessToken,
+		"refresh_token":          newToken.RefreshToken,
+		"previous_refresh_token": refreshToken,
+		"rotated_at":             time.Now().Unix(),
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("error marshalling user: %w", err)
 	}
 
-	expTime, err := strconv.Atoi(os.Getenv("JWT_REFRESH_EXPIRE_HOURS"))
-	if err != nil {
-		return nil, nil, fmt.Errorf("auth error: %w", err)
-	}
-
-	err = s.redisRepository.Set(context.Background(), sessionId, dataUser, time.Duration(expTime*int(time.Hour)))
+	err = s.redisRepository.Set(ctx, sessionId, dataUser, s.tokenIssuer.RefreshTTL())
 	if err != nil {
 		return nil, nil, fmt.Errorf("auth error: storing redis: %w", err)
 	}
@@ -88,26 +123,43 @@ func (s *userService) RefreshToken(refreshToken string, sessionId string) (*mode
 	return user, newToken, nil
 }
 
-func (s *userService) Authenticate(username string) (*models.User, *models.JwtToken, error) {
-	user, err := s.userRepository.GetUserByUsername(username)
+// Authenticate signs a user in. Accounts without a password hash still sign
+// in by username only, as before passwords were introduced. A username with
+// too many failed sign-ins is locked, see LockoutPolicy.
+func (s *userService) Authenticate(ctx context.Context, username string, password string) (*models.User, *models.JwtToken, error) {
+	locked, err := s.lockedFor(ctx, username)
 	if err != nil {
 		return nil, nil, err
 	}
+	if locked > 0 {
+		return nil, nil, &LoginLockedError{RetryAfter: locked}
+	}
+
+	user, err := s.userRepository.GetUserByUsername(ctx, username)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil, nil, s.failSignIn(ctx, username, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.PasswordHash != "" && !utils.CheckPassword(user.PasswordHash, password) {
+		return nil, nil, s.failSignIn(ctx, username, ErrInvalidCredentials)
+	}
+
+	if err := s.clearFailures(ctx, username); err != nil {
+		return nil, nil, err
+	}
 
 	timestamp := time.Now().Unix()
 	shortTimestamp := timestamp % 10000
 
 	sessionId := fmt.Sprintf("session:%d-%04d", user.UserId, shortTimestamp)
-	jwt, err := utils.GenerateSessionToken(sessionId)
+	jwt, err := s.tokenIssuer.GenerateSessionToken(sessionId)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	expTime, err := strconv.Atoi(os.Getenv("JWT_EXPIRE_HOURS"))
-	if err != nil {
-		return nil, nil, fmt.Errorf("auth error: %w", err)
-	}
-
 	dataUser, err := json.Marshal(map[string]interface{}{
 		"user_id":       user.UserId,
 		"timezone":      user.Timezone,
@@ -120,7 +172,7 @@ func (s *userService) Authenticate(username string) (*models.User, *models.JwtTo
 		return nil, nil, fmt.Errorf("error marshalling user: %w", err)
 	}
 
-	err = s.redisRepository.Set(context.Background(), sessionId, dataUser, time.Duration(expTime*int(time.Hour)))
+	err = s.redisRepository.Set(ctx, sessionId, dataUser, s.tokenIssuer.AccessTTL())
 	if err != nil {
 		return nil, nil, fmt.Errorf("auth error: storing redis: %w", err)
 	}
@@ -128,6 +180,69 @@ func (s *userService) Authenticate(username string) (*models.User, *models.JwtTo
 	return user, jwt, nil
 }
 
-func (s *userService) UpdateUserTimezone(userId int, timezone string) error {
-	return s.userRepository.UpdateUserTimezone(userId, timezone)
+// UpdateUserTimezone also drops the cached lists of the user, their times are
+// shown in it.
+func (s *userService) UpdateUserTimezone(ctx context.Context, userId int, timezone string) error {
+	err := s.userRepository.UpdateUserTimezone(ctx, userId, timezone)
+	if err != nil {
+		return err
+	}
+
+	s.listCache.Invalidate(ctx, userId)
+	return nil
+}
+
+func (s *userService) UpdateUserBuffers(ctx context.Context, userId int, bufferBefore int, bufferAfter int) error {
+	return s.userRepository.UpdateUserBuffers(ctx, userId, bufferBefore, bufferAfter)
+}
+
+func (s *userService) CreateUser(ctx context.Context, user *models.User, password string) (*models.User, error) {
+	if user.Role != models.UserRoleAdmin && user.Role != models.UserRoleStaff {
+		return nil, ErrInvalidRole
+	}
+
+	if _, err := time.LoadLocation(user.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, user.Timezone)
+	}
+
+	if len(password) < 8 {
+		return nil, ErrPasswordTooShort
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("error hash password: %w", err)
+	}
+
+	user.CreatedAt = time.Now().UTC()
+
+	return s.userRepository.InsertUser(ctx, user, hash)
+}
+
+func (s *userService) ResetPassword(ctx context.Context, username string, password string) error {
+	if len(password) < 8 {
+		return ErrPasswordTooShort
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("error hash password: %w", err)
+	}
+
+	return s.userRepository.UpdatePasswordHash(ctx, username, hash)
+}
+
+// DisableUser blocks the account and ends its open sessions.
+func (s *userService) DisableUser(ctx context.Context, username string) error {
+	userId, err := s.userRepository.DisableUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	err = s.redisRepository.DeleteByPattern(ctx, fmt.Sprintf("session:%d-*", userId))
+	if err != nil {
+		return fmt.Errorf("error end sessions: %w", err)
+	}
+
+	return nil
 }
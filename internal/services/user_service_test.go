@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+type fakeUserRepository struct {
+	repositories.UserRepository
+}
+
+func (r *fakeUserRepository) GetUserById(ctx context.Context, userId int) (*models.User, error) {
+	return &models.User{UserId: userId, Timezone: "UTC", Role: "staff"}, nil
+}
+
+type refreshFixture struct {
+	service UserService
+	redis   *miniredis.Miniredis
+	issuer  *utils.TokenIssuer
+}
+
+func newRefreshFixture(t *testing.T) *refreshFixture {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	issuer := utils.NewTokenIssuer("secret", "refresh-secret", time.Hour, 24*time.Hour)
+	service := NewUserService(&fakeUserRepository{}, repositories.NewRedisRepository(client), issuer, models.LockoutPolicy{}, nil)
+
+	return &refreshFixture{service: service, redis: mr, issuer: issuer}
+}
+
+// token issues a refresh token that differs from the others of the test.
+func (f *refreshFixture) token(t *testing.T, name string) string {
+	t.Helper()
+
+	token, err := f.issuer.GenerateSessionToken("session:" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token.RefreshToken
+}
+
+// session stores session:1 rotated from previous to current at rotatedAt.
+func (f *refreshFixture) session(t *testing.T, current, previous string, rotatedAt time.Time) {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]interface{}{
+		"user_id":                1,
+		"role":                   "staff",
+		"access_token":           "access",
+		"refresh_token":          current,
+		"previous_refresh_token": previous,
+		"rotated_at":             rotatedAt.Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.redis.Set("session:1", string(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	f := newRefreshFixture(t)
+	current, previous := f.token(t, "b"), f.token(t, "a")
+	f.session(t, current, previous, time.Now().Add(-time.Hour))
+
+	_, token, err := f.service.RefreshToken(context.Background(), current, "session:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := f.redis.Get("session:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(stored), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["refresh_token"] != token.RefreshToken || data["previous_refresh_token"] != current {
+		t.Fatalf("session not rotated from the presented token: %v", data)
+	}
+}
+
+func TestRefreshTokenAnswersPreviousWithinGrace(t *testing.T) {
+	f := newRefreshFixture(t)
+	current, previous := f.token(t, "b"), f.token(t, "a")
+	f.session(t, current, previous, time.Now())
+
+	_, token, err := f.service.RefreshToken(context.Background(), previous, "session:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.RefreshToken != current || token.AccessToken != "access" {
+		t.Fatalf("got %+v, want the tokens the previous one was rotated to", token)
+	}
+}
+
+func TestRefreshTokenRefusesReuse(t *testing.T) {
+	tests := []struct {
+		name      string
+		presented string
+		rotatedAt time.Time
+	}{
+		{"previous after the grace window", "a", time.Now().Add(-time.Minute)},
+		{"older than the previous", "c", time.Now()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRefreshFixture(t)
+			f.session(t, f.token(t, "b"), f.token(t, "a"), tt.rotatedAt)
+
+			_, _, err := f.service.RefreshToken(context.Background(), f.token(t, tt.presented), "session:1")
+			if !errors.Is(err, ErrRefreshTokenReused) {
+				t.Fatalf("got %v, want ErrRefreshTokenReused", err)
+			}
+			if !f.redis.Exists("session:1") {
+				t.Fatal("reuse ended the session of the current token holder")
+			}
+		})
+	}
+}
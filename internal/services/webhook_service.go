@@ -0,0 +1,323 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/apperror"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+const (
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	webhookLease        = 2 * time.Minute
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 10
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+)
+
+var (
+	ErrUnknownEventType  = apperror.Validation("unknown webhook event type")
+	ErrInvalidWebhookUrl = apperror.Validation("webhook url must be https and must not point to a loopback, private or link-local address")
+)
+
+type WebhookService interface {
+	CreateEndpoint(ctx context.Context, userId int, url string, eventTypes []string) (*models.WebhookEndpoint, error)
+	GetEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	DeactivateEndpoint(ctx context.Context, endpointId int) error
+
+	GetDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, deliveryId int64) (*models.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, deliveryId int64) error
+
+	RunDispatcher(ctx context.Context)
+}
+
+type webhookService struct {
+	webhookRepository repositories.WebhookRepository
+	client            *http.Client
+}
+
+// NewWebhookService sends deliveries with client, by default one refusing to
+// connect to loopback, private and link-local addresses whatever the url
+// resolves to.
+func NewWebhookService(webhookRepository repositories.WebhookRepository, client *http.Client) WebhookService {
+	if client == nil {
+		dialer := &net.Dialer{
+			Timeout: webhookTimeout,
+			Control: func(network, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+					return fmt.Errorf("webhook: refusing to connect to %s", address)
+				}
+				return nil
+			},
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		client = &http.Client{Timeout: webhookTimeout, Transport: transport}
+	}
+
+	return &webhookService{
+		webhookRepository: webhookRepository,
+		client:            client,
+	}
+}
+
+func (s *webhookService) CreateEndpoint(ctx context.Context, userId int, url string, eventTypes []string) (*models.WebhookEndpoint, error) {
+	if err := validateWebhookUrl(url); err != nil {
+		return nil, err
+	}
+
+	for _, eventType := range eventTypes {
+		if !isWebhookEventType(eventType) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+		}
+	}
+
+	secret, err := utils.NewWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		Url:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		IsActive:   true,
+		CreatedBy:  userId,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	return s.webhookRepository.InsertEndpoint(ctx, endpoint)
+}
+
+// validateWebhookUrl only accepts https urls whose host is not obviously the
+// API itself, another service of the internal network or the cloud metadata
+// service. Names resolving to such addresses are refused when connecting.
+func validateWebhookUrl(rawUrl string) error {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return ErrInvalidWebhookUrl
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrInvalidWebhookUrl
+	}
+
+	if ip := net.ParseIP(host); ip != nil && isInternalIP(ip) {
+		return ErrInvalidWebhookUrl
+	}
+
+	return nil
+}
+
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+func (s *webhookService) GetEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	return s.webhookRepository.GetEndpoints(ctx)
+}
+
+func (s *webhookService) DeactivateEndpoint(ctx context.Context, endpointId int) error {
+	return s.webhookRepository.DeactivateEndpoint(ctx, endpointId)
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]models.WebhookDelivery, error) {
+	return s.webhookRepository.GetDeliveries(ctx, endpointId, limit, offset)
+}
+
+func (s *webhookService) GetDelivery(ctx context.Context, deliveryId int64) (*models.WebhookDelivery, error) {
+	return s.webhookRepository.GetDeliveryById(ctx, deliveryId)
+}
+
+func (s *webhookService) ReplayDelivery(ctx context.Context, deliveryId int64) error {
+	return s.webhookRepository.ReplayDelivery(ctx, deliveryId)
+}
+
+// RunDispatcher sends due deliveries until ctx is done. Several dispatchers
+// may run side by side, claims keep them from sending the same delivery.
+func (s *webhookService) RunDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			deliveries, err := s.webhookRepository.ClaimDueDeliveries(ctx, webhookBatchSize, webhookLease)
+			if err != nil {
+				slog.ErrorContext(ctx, "claiming webhook deliveries", "error", err.Error())
+				break
+			}
+
+			for _, delivery := range deliveries {
+				s.deliver(ctx, delivery)
+			}
+
+			if len(deliveries) < webhookBatchSize {
+				break
+			}
+		}
+	}
+}
+
+func (s *webhookService) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	attempt := models.WebhookDeliveryAttempt{AttemptedAt: time.Now().UTC()}
+
+	code, err := s.send(ctx, delivery)
+	attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+	if code != 0 {
+		attempt.ResponseCode = &code
+	}
+
+	status := models.WebhookDeliverySucceeded
+	nextAttemptAt := attempt.AttemptedAt
+
+	if err != nil {
+		attempt.Error = err.Error()
+		status = models.WebhookDeliveryPending
+		nextAttemptAt = attempt.AttemptedAt.Add(webhookBackoff(delivery.Attempts + 1))
+
+		if delivery.Attempts+1 >= webhookMaxAttempts {
+			status = models.WebhookDeliveryFailed
+		}
+	}
+
+	// record the attempt even when shutting down, a sent delivery must not
+	// be sent again only because the worker stopped
+	if err := s.webhookRepository.RecordAttempt(context.WithoutCancel(ctx), delivery.WebhookDeliveryId, attempt, status, nextAttemptAt); err != nil {
+		slog.ErrorContext(ctx, "recording webhook attempt", "delivery_id", delivery.WebhookDeliveryId,
+			"type", delivery.EventType, "error", err.Error())
+	}
+}
+
+// send posts the payload and returns the response code, 0 without response.
+// Any status outside 2xx is a failed attempt.
+func (s *webhookService) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "be-appointment-system-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.WebhookDeliveryId, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", utils.SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// webhookBackoff doubles the wait after every failed attempt, capped.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+func isWebhookEventType(eventType string) bool {
+	for _, t := range models.WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookOutboxHandler queues deliveries of outbox events to the endpoints
+// subscribed to them. Deliveries are written in the relay transaction, so
+// every event is queued exactly once.
+type webhookOutboxHandler struct {
+	webhookRepository repositories.WebhookRepository
+}
+
+func NewWebhookOutboxHandler(webhookRepository repositories.WebhookRepository) OutboxHandler {
+	return &webhookOutboxHandler{webhookRepository: webhookRepository}
+}
+
+func (h *webhookOutboxHandler) Name() string {
+	return OutboxHandlerWebhooks
+}
+
+func (h *webhookOutboxHandler) Handle(ctx context.Context, tx *sql.Tx, event models.OutboxEvent) error {
+	if !isWebhookEventType(event.EventType) {
+		return nil
+	}
+
+	payload, err := json.Marshal(models.WebhookPayload{
+		Id:        fmt.Sprintf("evt_%d", event.OutboxEventId),
+		Type:      event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling webhook payload: %w", err)
+	}
+
+	if err := h.webhookRepository.EnqueueDeliveries(ctx, tx, event.EventType, payload); err != nil {
+		return fmt.Errorf("error enqueue webhook: %w", err)
+	}
+
+	return nil
+}
+
+// invitationWebhookEvent maps an RSVP status to its webhook event type.
+func invitationWebhookEvent(status string) string {
+	switch status {
+	case models.InvitationStatusAccepted:
+		return models.WebhookEventInvitationAccepted
+	case models.InvitationStatusRejected:
+		return models.WebhookEventInvitationRejected
+	case models.InvitationStatusWaitlisted:
+		return models.WebhookEventInvitationWaitlisted
+	default:
+		return models.WebhookEventInvitationCreated
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+)
+
+// attemptRecorder keeps the attempts recorded by the dispatcher.
+type attemptRecorder struct {
+	repositories.WebhookRepository
+	attempt       models.WebhookDeliveryAttempt
+	status        string
+	nextAttemptAt time.Time
+}
+
+func (r *attemptRecorder) RecordAttempt(ctx context.Context, deliveryId int64, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	r.attempt, r.status, r.nextAttemptAt = attempt, status, nextAttemptAt
+	return nil
+}
+
+// newWebhookReceiver starts an endpoint that checks the signature of every
+// request with secret and answers with status.
+func newWebhookReceiver(t *testing.T, secret string, status int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		timestamp, err := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		if err != nil {
+			t.Errorf("bad timestamp header: %v", err)
+		}
+		if !utils.VerifyWebhook(secret, timestamp, body, r.Header.Get("X-Webhook-Signature")) {
+			t.Error("signature does not verify with the endpoint secret")
+		}
+		if r.Header.Get("X-Webhook-Event") != models.WebhookEventAppointmentCreated {
+			t.Errorf("event header %q", r.Header.Get("X-Webhook-Event"))
+		}
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		want     string
+		backoff  time.Duration
+	}{
+		{"delivered", http.StatusNoContent, 0, models.WebhookDeliverySucceeded, 0},
+		{"first failure", http.StatusInternalServerError, 0, models.WebhookDeliveryPending, webhookBaseBackoff},
+		{"third failure", http.StatusBadGateway, 2, models.WebhookDeliveryPending, 4 * webhookBaseBackoff},
+		{"last failure", http.StatusServiceUnavailable, webhookMaxAttempts - 1, models.WebhookDeliveryFailed, 512 * webhookBaseBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := "whsec_test"
+			server := newWebhookReceiver(t, secret, tt.status)
+			recorder := &attemptRecorder{}
+			service := NewWebhookService(recorder, server.Client()).(*webhookService)
+
+			service.deliver(context.Background(), models.WebhookDelivery{
+				WebhookDeliveryId: 1,
+				EventType:         models.WebhookEventAppointmentCreated,
+				Payload:           []byte(`{"id":"evt_1"}`),
+				Attempts:          tt.attempts,
+				Url:               server.URL,
+				Secret:            secret,
+			})
+
+			if recorder.status != tt.want {
+				t.Errorf("status %q, want %q", recorder.status, tt.want)
+			}
+			if code := recorder.attempt.ResponseCode; code == nil || *code != tt.status {
+				t.Errorf("response code %v, want %d", code, tt.status)
+			}
+			if backoff := recorder.nextAttemptAt.Sub(recorder.attempt.AttemptedAt); backoff != tt.backoff {
+				t.Errorf("next attempt after %v, want %v", backoff, tt.backoff)
+			}
+		})
+	}
+}
+
+func TestWebhookDefaultClientRefusesInternalAddresses(t *testing.T) {
+	server := newWebhookReceiver(t, "whsec_test", http.StatusNoContent)
+	recorder := &attemptRecorder{}
+	service := NewWebhookService(recorder, nil).(*webhookService)
+
+	service.deliver(context.Background(), models.WebhookDelivery{
+		WebhookDeliveryId: 1,
+		EventType:         models.WebhookEventAppointmentCreated,
+		Payload:           []byte(`{}`),
+		Url:               server.URL,
+		Secret:            "whsec_test",
+	})
+
+	if recorder.status != models.WebhookDeliveryPending || recorder.attempt.ResponseCode != nil {
+		t.Fatalf("delivery to %s went through: %+v", server.URL, recorder.attempt)
+	}
+}
+
+func TestValidateWebhookUrl(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://crm.example.com/hooks", true},
+		{"https://203.0.113.10/hooks", true},
+		{"http://crm.example.com/hooks", false},
+		{"https://localhost/hooks", false},
+		{"https://api.localhost./hooks", false},
+		{"https://127.0.0.1/hooks", false},
+		{"https://10.0.0.5/hooks", false},
+		{"https://192.168.1.1/hooks", false},
+		{"https://[fd00::1]/hooks", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://0.0.0.0/hooks", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		if err := validateWebhookUrl(tt.url); (err == nil) != tt.valid {
+			t.Errorf("validateWebhookUrl(%q) = %v, valid %v", tt.url, err, tt.valid)
+		}
+	}
+}
+
+func TestIsInternalIP(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.0.1", "fd12::1", "169.254.169.254", "fe80::1", "0.0.0.0"} {
+		if !isInternalIP(net.ParseIP(addr)) {
+			t.Errorf("%s is not internal", addr)
+		}
+	}
+	for _, addr := range []string{"203.0.113.10", "8.8.8.8", "2001:db8::1"} {
+		if isInternalIP(net.ParseIP(addr)) {
+			t.Errorf("%s is internal", addr)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ghofaralhasyim/be-appointment-system/internal/config"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/repositories"
+	"github.com/ghofaralhasyim/be-appointment-system/internal/services"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	JobOutboxPrune = "outbox.prune"
+
+	outboxRetention     = 7 * 24 * time.Hour
+	outboxPruneInterval = time.Hour
+)
+
+// Run does the background work of the worker process: relaying the outbox,
+// sending webhooks and running jobs. It returns once ctx is done and the jobs
+// in progress were settled.
+func Run(ctx context.Context, db *sql.DB, redisClient *redis.Client, cfg config.WorkerConfig) {
+	redisRepo := repositories.NewRedisRepository(redisClient)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	jobRepo := repositories.NewJobRepository(redisClient)
+
+	realtimeService := services.NewRealtimeService(redisRepo)
+	webhookService := services.NewWebhookService(webhookRepo, nil)
+	jobService := services.NewJobService(jobRepo)
+
+	outboxHandlers := []services.OutboxHandler{
+		services.NewRealtimeOutboxHandler(realtimeService),
+		services.NewWebhookOutboxHandler(webhookRepo),
+	}
+
+	outboxRelay := services.NewOutboxRelay(outboxRepo)
+	for _, handler := range outboxHandlers {
+		outboxRelay.Register(handler)
+	}
+
+	jobService.Register(JobOutboxPrune, func(ctx context.Context, job models.Job) error {
+		var names []string
+		for _, handler := range outboxHandlers {
+			names = append(names, handler.Name())
+		}
+
+		pruned, err := outboxRepo.PruneProcessed(ctx, time.Now().Add(-outboxRetention), names)
+		if err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "outbox pruned", "job_id", job.JobId, "type", job.Type, "pruned", pruned)
+		return nil
+	})
+
+	concurrency := cfg.Concurrency
+
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	run(func() { outboxRelay.Run(ctx) })
+	run(func() { webhookService.RunDispatcher(ctx) })
+	run(func() { jobService.Run(ctx, concurrency) })
+	run(func() { schedule(ctx, jobService, JobOutboxPrune, outboxPruneInterval) })
+
+	slog.InfoContext(ctx, "worker started", "concurrency", concurrency)
+	wg.Wait()
+}
+
+// schedule enqueues a job of the given type every interval. The unique key
+// keeps workers running side by side from queueing it more than once.
+func schedule(ctx context.Context, jobService services.JobService, jobType string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := jobService.Enqueue(ctx, jobType, nil, models.JobOptions{UniqueKey: jobType})
+		if err != nil && !errors.Is(err, repositories.ErrDuplicateJob) {
+			slog.ErrorContext(ctx, "scheduling job", "type", jobType, "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+// The migrations up to qualifiedVersion name their objects in legacySchema.
+// Run on any other schema they are rewritten to create them in that one.
+const (
+	legacySchema     = "stg_appointment"
+	qualifiedVersion = 13
+)
+
+var (
+	ErrDirtyMigration      = errors.New("database is dirty, a migration failed half way and needs a manual fix")
+	ErrSchemaVersion       = errors.New("database schema is not at the version this build expects")
+	ErrConcurrentMigration = errors.New("another migration of the schema ran at the same time")
+)
+
+var (
+	legacyQualifier       = regexp.MustCompile(`\bstg_appointment\.`)
+	legacySchemaStatement = regexp.MustCompile(`(?m)^(CREATE|DROP) SCHEMA [^;]*\bstg_appointment\b[^;]*;\n?`)
+)
+
+// Migration is a pair of embedded up/down SQL files sharing a version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// Migrator applies the embedded migrations to one schema. The version is
+// kept in the layout of golang-migrate, in public.schema_migrations for
+// stg_appointment, so databases migrated with that tool carry on where they
+// are, and in the schema_migrations table of the schema for any other.
+// Migrations of all schemas of a database take turns on an advisory lock.
+type Migrator struct {
+	db         *sql.DB
+	schema     string
+	migrations []Migration
+}
+
+// NewMigrator prepares the migrations of the schema. The connection must use
+// the schema as the first entry of its search_path, see config.PostgresConfig.DSN.
+func NewMigrator(db *sql.DB, schema string) (*Migrator, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, schema: schema, migrations: migrations}, nil
+}
+
+// LoadMigrations reads the embedded files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, sorted by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionPart, title, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>", name)
+		}
+
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up or down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Version returns the current version, 0 on an empty schema.
+func (m *Migrator) Version() (int, bool, error) {
+	var exists bool
+	err := m.db.QueryRow(`SELECT to_regclass($1) IS NOT NULL;`, m.versionTable()).Scan(&exists)
+	if err != nil {
+		return 0, false, fmt.Errorf("error read schema version: %w", err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var version int
+	var dirty bool
+
+	err = m.db.QueryRow(`SELECT version, dirty FROM `+m.versionTable()+` LIMIT 1;`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("error read schema version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Latest returns the version of the newest embedded migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Verify fails unless the schema is at the latest version, so a build never
+// serves a database it was not written for.
+func (m *Migrator) Verify() error {
+	current, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w (version %d)", ErrDirtyMigration, current)
+	}
+
+	if current != m.Latest() {
+		return fmt.Errorf("%w: schema %s is at version %d, expected %d, run \"migrate up\"",
+			ErrSchemaVersion, m.schema, current, m.Latest())
+	}
+
+	return nil
+}
+
+func (m *Migrator) versionTable() string {
+	if m.schema == legacySchema {
+		return "public.schema_migrations"
+	}
+	return pq.QuoteIdentifier(m.schema) + ".schema_migrations"
+}
+
+// searchPath is the search_path a migration runs with. Rewritten migrations
+// name every object, public comes first so btree_gist is installed there.
+// Another schema of the database may have installed it in legacySchema.
+func (m *Migrator) searchPath(version int) string {
+	if m.schema != legacySchema && version <= qualifiedVersion {
+		return "public, " + legacySchema
+	}
+	return pq.QuoteIdentifier(m.schema) + ", public"
+}
+
+// query returns the SQL of a migration as run on the schema. The migrations
+// naming legacySchema have it replaced by the schema, whose creation and
+// removal is left to the migrator.
+func (m *Migrator) query(version int, query string) string {
+	if m.schema == legacySchema || version > qualifiedVersion {
+		return query
+	}
+
+	query = legacySchemaStatement.ReplaceAllString(query, "")
+	return legacyQualifier.ReplaceAllString(query, pq.QuoteIdentifier(m.schema)+".")
+}
+
+// lock makes tx wait for the migrations of the other schemas of the
+// database. They share btree_gist and a CREATE IF NOT EXISTS racing another
+// one fails.
+func lock(tx *sql.Tx) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('schema_migrations'));`)
+	return err
+}
+
+// prepare creates the version table, and the schema unless it is
+// legacySchema, which the first migration creates.
+func (m *Migrator) prepare() (err error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if err = lock(tx); err != nil {
+		return err
+	}
+
+	if m.schema != legacySchema {
+		_, err = tx.Exec(`CREATE SCHEMA IF NOT EXISTS ` + pq.QuoteIdentifier(m.schema) + `;`)
+		if err != nil {
+			return fmt.Errorf("error create schema %s: %w", m.schema, err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS ` + m.versionTable() + ` (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("error create schema_migrations: %w", err)
+	}
+
+	return nil
+}
+
+// Up applies all pending migrations and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	current, err := m.cleanVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range m.migrations {
+		if migration.Version <= current {
+			continue
+		}
+
+		if err := m.apply(migration.Version, migration.Up, current, migration.Version); err != nil {
+			return applied, fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+		current = migration.Version
+	}
+
+	return applied, nil
+}
+
+// Down reverts the given number of applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	current, err := m.cleanVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if current > 0 && !m.known(current) {
+		return nil, fmt.Errorf("database is at version %d which this build does not know", current)
+	}
+
+	var reverted []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if migration.Version > current {
+			continue
+		}
+
+		previous := 0
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+
+		if err := m.apply(migration.Version, migration.Down, migration.Version, previous); err != nil {
+			return reverted, fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+		}
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// Status lists every embedded migration and whether it is applied.
+func (m *Migrator) Status() ([]MigrationStatus, int, bool, error) {
+	current, dirty, err := m.Version()
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			Applied:   migration.Version <= current,
+		})
+	}
+
+	return statuses, current, dirty, nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) cleanVersion() (int, error) {
+	if err := m.prepare(); err != nil {
+		return 0, err
+	}
+
+	current, dirty, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w (version %d)", ErrDirtyMigration, current)
+	}
+
+	return current, nil
+}
+
+// apply runs the SQL of the migration and moves the schema from version from
+// to version to in one transaction, so a failing migration leaves the
+// database as it was. It fails with ErrConcurrentMigration when the schema
+// is no longer at from once it has the lock.
+func (m *Migrator) apply(migrationVersion int, query string, from int, to int) (err error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if err = lock(tx); err != nil {
+		return err
+	}
+
+	var current int
+	err = tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM ` + m.versionTable() + `;`).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if current != from {
+		err = fmt.Errorf("%w, it is at version %d now", ErrConcurrentMigration, current)
+		return err
+	}
+
+	if _, err = tx.Exec(`SET LOCAL search_path TO ` + m.searchPath(migrationVersion) + `;`); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(m.query(migrationVersion, query)); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`DELETE FROM ` + m.versionTable() + `;`); err != nil {
+		return err
+	}
+
+	if to > 0 {
+		_, err = tx.Exec(`INSERT INTO `+m.versionTable()+` (version, dirty) VALUES ($1, FALSE);`, to)
+	}
+
+	return err
+}
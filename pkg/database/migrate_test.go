@@ -0,0 +1,196 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestQueryCreatesObjectsInTheSchema(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Migrator{schema: "staging", migrations: migrations}
+	for _, migration := range migrations {
+		if migration.Version > qualifiedVersion {
+			continue
+		}
+
+		for direction, query := range map[string]string{"up": migration.Up, "down": migration.Down} {
+			got := m.query(migration.Version, query)
+			if strings.Contains(got, legacySchema) {
+				t.Errorf("%d_%s %s still names %s:\n%s", migration.Version, migration.Name, direction, legacySchema, got)
+			}
+			if strings.Contains(got, "SCHEMA") {
+				t.Errorf("%d_%s %s creates or drops a schema:\n%s", migration.Version, migration.Name, direction, got)
+			}
+		}
+	}
+
+	if got := m.query(1, migrations[0].Up); !strings.Contains(got, `CREATE TABLE "staging".users`) {
+		t.Errorf("first migration does not create users in staging:\n%s", got)
+	}
+}
+
+func TestQueryKeepsLegacyAndLaterMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := &Migrator{schema: legacySchema, migrations: migrations}
+	staging := &Migrator{schema: "staging", migrations: migrations}
+	for _, migration := range migrations {
+		if got := legacy.query(migration.Version, migration.Up); got != migration.Up {
+			t.Errorf("%d_%s rewritten for %s", migration.Version, migration.Name, legacySchema)
+		}
+		if migration.Version > qualifiedVersion && staging.query(migration.Version, migration.Up) != migration.Up {
+			t.Errorf("%d_%s rewritten although it names no schema", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	tests := []struct {
+		schema  string
+		version int
+		want    string
+	}{
+		{legacySchema, 1, `"stg_appointment", public`},
+		{legacySchema, 20, `"stg_appointment", public`},
+		{"staging", 4, "public, stg_appointment"},
+		{"staging", qualifiedVersion + 1, `"staging", public`},
+		{"staging", 20, `"staging", public`},
+	}
+	for _, tt := range tests {
+		m := &Migrator{schema: tt.schema}
+		if got := m.searchPath(tt.version); got != tt.want {
+			t.Errorf("searchPath of %s at %d = %s, want %s", tt.schema, tt.version, got, tt.want)
+		}
+	}
+}
+
+// TestMigrateTwoSchemas migrates two schemas of one database at the same
+// time, then takes one down again. It needs a PostgreSQL database named by
+// TEST_POSTGRES_DSN.
+func TestMigrateTwoSchemas(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	schemas := []string{"migrate_test_a", "migrate_test_b"}
+	for _, schema := range schemas {
+		schema := schema
+		t.Cleanup(func() {
+			db.Exec(`DROP SCHEMA IF EXISTS ` + pq.QuoteIdentifier(schema) + ` CASCADE;`)
+		})
+	}
+
+	migrators := make([]*Migrator, len(schemas))
+	for i, schema := range schemas {
+		if migrators[i], err = NewMigrator(db, schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(migrators))
+	for i := range migrators {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = migrators[i].Up()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, m := range migrators {
+		if errs[i] != nil {
+			t.Fatalf("up %s: %v", m.schema, errs[i])
+		}
+		if err := m.Verify(); err != nil {
+			t.Fatalf("verify %s: %v", m.schema, err)
+		}
+	}
+
+	tables := func(schema string) int {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pg_tables WHERE schemaname = $1 AND tablename <> 'schema_migrations';`, schema).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	a, b := tables(schemas[0]), tables(schemas[1])
+	if a == 0 || a != b {
+		t.Fatalf("%s has %d tables, %s has %d", schemas[0], a, schemas[1], b)
+	}
+
+	_, err = db.Exec(`INSERT INTO "migrate_test_a".users (name, username, timezone) VALUES ('A', 'a', 'UTC');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM "migrate_test_b".users;`).Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	if users != 0 {
+		t.Fatalf("user written to %s shows up in %s", schemas[0], schemas[1])
+	}
+
+	if _, err := migrators[0].Down(len(migrators[0].migrations)); err != nil {
+		t.Fatalf("down %s: %v", schemas[0], err)
+	}
+	if left := tables(schemas[0]); left != 0 {
+		t.Errorf("%s keeps %d tables after down", schemas[0], left)
+	}
+	if err := migrators[1].Verify(); err != nil {
+		t.Errorf("%s after %s went down: %v", schemas[1], schemas[0], err)
+	}
+	if left := tables(schemas[1]); left != b {
+		t.Errorf("%s has %d tables after %s went down, want %d", schemas[1], left, schemas[0], b)
+	}
+}
+
+func TestApplyRefusesRacedVersion(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	t.Cleanup(func() { db.Exec(`DROP SCHEMA IF EXISTS "migrate_test_race" CASCADE;`) })
+
+	m, err := NewMigrator(db, "migrate_test_race")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a second process that read the version before the first one applied
+	first := m.migrations[0]
+	if err := m.apply(first.Version, first.Up, 0, first.Version); !errors.Is(err, ErrConcurrentMigration) {
+		t.Fatalf("got %v, want ErrConcurrentMigration", err)
+	}
+}
@@ -1,21 +1,34 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"log"
 	"time"
 
-	"github.com/ghofaralhasyim/be-appointment-system/pkg/utils"
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func InitDbConnection() (*sql.DB, error) {
-	connUrl, errConnUrl := utils.ConnURLBuilder("postgres")
-	if errConnUrl != nil {
-		return nil, errConnUrl
-	}
-
-	db, err := sql.Open("postgres", connUrl)
+// InitDbConnection opens the pool with every statement traced. Spans are only
+// recorded under an existing span, e.g. of an HTTP request, so background
+// polling does not start a trace of its own.
+func InitDbConnection(dsn string) (*sql.DB, error) {
+	db, err := otelsql.Open("postgres", dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			DisableErrSkip:       true,
+			SpanFilter: func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
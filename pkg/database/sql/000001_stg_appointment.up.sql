@@ -1,4 +1,4 @@
-CREATE SCHEMA stg_appointment;
+CREATE SCHEMA IF NOT EXISTS stg_appointment;
 
 CREATE TABLE stg_appointment.users (
     user_id SERIAL PRIMARY KEY,
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS stg_appointment.idx_invitations_appointment_role;
+
+ALTER TABLE stg_appointment.appointments DROP COLUMN IF EXISTS quorum;
+ALTER TABLE stg_appointment.invitations DROP COLUMN IF EXISTS role;
@@ -0,0 +1,7 @@
+ALTER TABLE stg_appointment.invitations
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'required';  -- required, optional, fyi
+
+ALTER TABLE stg_appointment.appointments
+    ADD COLUMN quorum INT DEFAULT NULL;  -- required acceptances needed to confirm, NULL means all required
+
+CREATE INDEX idx_invitations_appointment_role ON stg_appointment.invitations (appointment_id, role);
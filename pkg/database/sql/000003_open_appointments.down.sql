@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS stg_appointment.idx_appointments_open;
+DROP INDEX IF EXISTS stg_appointment.idx_invitations_waitlist;
+DROP INDEX IF EXISTS stg_appointment.idx_invitations_appointment_invitee;
+
+ALTER TABLE stg_appointment.appointments
+    DROP COLUMN IF EXISTS capacity,
+    DROP COLUMN IF EXISTS is_open;
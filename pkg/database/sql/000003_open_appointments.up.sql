@@ -0,0 +1,12 @@
+ALTER TABLE stg_appointment.appointments
+    ADD COLUMN is_open BOOLEAN NOT NULL DEFAULT FALSE,  -- users can self-join open appointments
+    ADD COLUMN capacity INT DEFAULT NULL;                -- max accepted attendees, NULL means unlimited
+
+-- a user holds at most one invitation per appointment, also guards concurrent joins
+CREATE UNIQUE INDEX idx_invitations_appointment_invitee ON stg_appointment.invitations (appointment_id, invitee_id);
+
+-- FIFO waitlist lookup
+CREATE INDEX idx_invitations_waitlist ON stg_appointment.invitations (appointment_id, created_at, invitation_id)
+    WHERE status = 'waitlisted';
+
+CREATE INDEX idx_appointments_open ON stg_appointment.appointments (start_time) WHERE is_open;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stg_appointment.appointment_resources;
+DROP TABLE IF EXISTS stg_appointment.resources;
@@ -0,0 +1,23 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE stg_appointment.resources (
+    resource_id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,  -- room, equipment, vehicle
+    capacity INT DEFAULT NULL,
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ DEFAULT NULL
+);
+
+CREATE TABLE stg_appointment.appointment_resources (
+    appointment_id INT NOT NULL REFERENCES stg_appointment.appointments(appointment_id) ON DELETE CASCADE,
+    resource_id INT NOT NULL REFERENCES stg_appointment.resources(resource_id) ON DELETE CASCADE,
+    during TSTZRANGE NOT NULL,
+    PRIMARY KEY (appointment_id, resource_id),
+    -- a resource can never be booked twice for overlapping time ranges
+    CONSTRAINT appointment_resources_no_overlap EXCLUDE USING gist (resource_id WITH =, during WITH &&)
+);
+
+CREATE INDEX idx_resources_type ON stg_appointment.resources (type);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS stg_appointment.idx_appointments_event_type;
+ALTER TABLE stg_appointment.appointments DROP COLUMN IF EXISTS event_type_id;
+
+DROP TABLE IF EXISTS stg_appointment.appointment_guests;
+DROP TABLE IF EXISTS stg_appointment.event_types;
+DROP TABLE IF EXISTS stg_appointment.booking_pages;
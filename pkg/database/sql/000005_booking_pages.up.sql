@@ -0,0 +1,40 @@
+CREATE TABLE stg_appointment.booking_pages (
+    booking_page_id SERIAL PRIMARY KEY,
+    host_id INT NOT NULL REFERENCES stg_appointment.users(user_id) ON DELETE CASCADE,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE stg_appointment.event_types (
+    event_type_id SERIAL PRIMARY KEY,
+    booking_page_id INT NOT NULL REFERENCES stg_appointment.booking_pages(booking_page_id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    duration_minutes INT NOT NULL CHECK (duration_minutes > 0),
+    buffer_before_minutes INT NOT NULL DEFAULT 0,
+    buffer_after_minutes INT NOT NULL DEFAULT 0,
+    min_notice_minutes INT NOT NULL DEFAULT 0,
+    max_per_day INT DEFAULT NULL,
+    availability JSONB NOT NULL DEFAULT '[]',  -- [{"weekday": 1, "start": "09:00", "end": "17:00"}] in host timezone
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- external people booked through a booking page, they have no user account
+CREATE TABLE stg_appointment.appointment_guests (
+    guest_id SERIAL PRIMARY KEY,
+    appointment_id INT NOT NULL REFERENCES stg_appointment.appointments(appointment_id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    notes VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+ALTER TABLE stg_appointment.appointments
+    ADD COLUMN event_type_id INT DEFAULT NULL REFERENCES stg_appointment.event_types(event_type_id) ON DELETE SET NULL;
+
+CREATE INDEX idx_booking_pages_host_id ON stg_appointment.booking_pages (host_id);
+CREATE INDEX idx_event_types_booking_page_id ON stg_appointment.event_types (booking_page_id);
+CREATE INDEX idx_appointment_guests_appointment_id ON stg_appointment.appointment_guests (appointment_id);
+CREATE INDEX idx_appointments_event_type ON stg_appointment.appointments (event_type_id, start_time);
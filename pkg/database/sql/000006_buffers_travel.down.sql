@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS stg_appointment.idx_invitations_invitee_status;
+
+ALTER TABLE stg_appointment.appointments
+    DROP COLUMN IF EXISTS travel_minutes,
+    DROP COLUMN IF EXISTS location;
+
+ALTER TABLE stg_appointment.users
+    DROP COLUMN IF EXISTS buffer_after_minutes,
+    DROP COLUMN IF EXISTS buffer_before_minutes;
@@ -0,0 +1,9 @@
+ALTER TABLE stg_appointment.users
+    ADD COLUMN buffer_before_minutes INT NOT NULL DEFAULT 0,  -- kept free before every appointment
+    ADD COLUMN buffer_after_minutes INT NOT NULL DEFAULT 0;   -- kept free after every appointment
+
+ALTER TABLE stg_appointment.appointments
+    ADD COLUMN location VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN travel_minutes INT NOT NULL DEFAULT 0;  -- travel padding on both sides of the appointment
+
+CREATE INDEX idx_invitations_invitee_status ON stg_appointment.invitations (invitee_id, status);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stg_appointment.holidays;
+DROP TABLE IF EXISTS stg_appointment.holiday_calendars;
+DROP TABLE IF EXISTS stg_appointment.time_off;
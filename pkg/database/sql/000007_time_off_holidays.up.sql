@@ -0,0 +1,31 @@
+CREATE TABLE stg_appointment.time_off (
+    time_off_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES stg_appointment.users(user_id) ON DELETE CASCADE,
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time TIMESTAMPTZ NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    auto_reply VARCHAR(255) NOT NULL DEFAULT '',  -- added to invitations received during the absence
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (end_time > start_time)
+);
+
+-- organization wide holiday calendars, dates are read in each user's timezone
+CREATE TABLE stg_appointment.holiday_calendars (
+    holiday_calendar_id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE stg_appointment.holidays (
+    holiday_id SERIAL PRIMARY KEY,
+    holiday_calendar_id INT NOT NULL REFERENCES stg_appointment.holiday_calendars(holiday_calendar_id) ON DELETE CASCADE,
+    uid VARCHAR(255) NOT NULL,  -- ICS UID, makes re-imports idempotent
+    name VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,     -- exclusive
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (holiday_calendar_id, uid)
+);
+
+CREATE INDEX idx_time_off_user_time ON stg_appointment.time_off (user_id, start_time, end_time);
+CREATE INDEX idx_holidays_dates ON stg_appointment.holidays (start_date, end_date);
@@ -0,0 +1,4 @@
+ALTER TABLE stg_appointment.appointments
+    DROP COLUMN IF EXISTS join_url,
+    DROP COLUMN IF EXISTS meeting_provider,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,4 @@
+ALTER TABLE stg_appointment.appointments
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',            -- sanitized HTML
+    ADD COLUMN meeting_provider VARCHAR(50) NOT NULL DEFAULT '', -- provider that generated join_url
+    ADD COLUMN join_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stg_appointment.audit_events;
+DROP FUNCTION IF EXISTS stg_appointment.reject_audit_change();
@@ -0,0 +1,31 @@
+CREATE TABLE stg_appointment.audit_events (
+    audit_event_id BIGSERIAL PRIMARY KEY,
+    appointment_id INT NOT NULL,  -- no foreign key, history outlives the appointment
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    actor_id INT,                 -- NULL for external guests
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    before JSONB NOT NULL DEFAULT '{}',
+    after JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_appointment ON stg_appointment.audit_events (appointment_id, created_at);
+CREATE INDEX idx_audit_events_actor ON stg_appointment.audit_events (actor_id, created_at);
+CREATE INDEX idx_audit_events_created_at ON stg_appointment.audit_events (created_at);
+
+-- audit events are append only
+CREATE FUNCTION stg_appointment.reject_audit_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit events are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_immutable
+    BEFORE UPDATE OR DELETE ON stg_appointment.audit_events
+    FOR EACH ROW EXECUTE FUNCTION stg_appointment.reject_audit_change();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON stg_appointment.audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION stg_appointment.reject_audit_change();
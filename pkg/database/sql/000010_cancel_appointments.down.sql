@@ -0,0 +1,2 @@
+ALTER TABLE stg_appointment.appointments
+    DROP COLUMN IF EXISTS cancelled_at;
@@ -0,0 +1,2 @@
+ALTER TABLE stg_appointment.appointments
+    ADD COLUMN cancelled_at TIMESTAMPTZ DEFAULT NULL;
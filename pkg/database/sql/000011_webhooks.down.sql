@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stg_appointment.webhook_delivery_attempts;
+DROP TABLE IF EXISTS stg_appointment.webhook_deliveries;
+DROP TABLE IF EXISTS stg_appointment.webhook_endpoints;
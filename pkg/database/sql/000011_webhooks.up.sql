@@ -0,0 +1,36 @@
+CREATE TABLE stg_appointment.webhook_endpoints (
+    webhook_endpoint_id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(100) NOT NULL,  -- HMAC-SHA256 signing key
+    event_types TEXT[] NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT REFERENCES stg_appointment.users(user_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE stg_appointment.webhook_deliveries (
+    webhook_delivery_id BIGSERIAL PRIMARY KEY,
+    webhook_endpoint_id INT NOT NULL REFERENCES stg_appointment.webhook_endpoints(webhook_endpoint_id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, succeeded, failed
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_response_code INT,
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE stg_appointment.webhook_delivery_attempts (
+    webhook_delivery_attempt_id BIGSERIAL PRIMARY KEY,
+    webhook_delivery_id BIGINT NOT NULL REFERENCES stg_appointment.webhook_deliveries(webhook_delivery_id) ON DELETE CASCADE,
+    response_code INT,            -- NULL when no response was received
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms INT NOT NULL,
+    attempted_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON stg_appointment.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_endpoint ON stg_appointment.webhook_deliveries (webhook_endpoint_id, created_at);
+CREATE INDEX idx_webhook_delivery_attempts_delivery ON stg_appointment.webhook_delivery_attempts (webhook_delivery_id);
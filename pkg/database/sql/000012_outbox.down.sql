@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stg_appointment.outbox_processed;
+DROP TABLE IF EXISTS stg_appointment.outbox_events;
@@ -0,0 +1,18 @@
+CREATE TABLE stg_appointment.outbox_events (
+    outbox_event_id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    appointment_id INT NOT NULL,
+    recipients INT[] NOT NULL DEFAULT '{}',  -- users to push the event to, empty for server-side only events
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- one row per event and relay handler, written in the transaction of the handler
+CREATE TABLE stg_appointment.outbox_processed (
+    outbox_event_id BIGINT NOT NULL REFERENCES stg_appointment.outbox_events(outbox_event_id) ON DELETE CASCADE,
+    handler VARCHAR(50) NOT NULL,
+    processed_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (outbox_event_id, handler)
+);
+
+CREATE INDEX idx_outbox_processed_handler ON stg_appointment.outbox_processed (handler, outbox_event_id);
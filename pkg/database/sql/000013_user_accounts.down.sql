@@ -0,0 +1,3 @@
+ALTER TABLE stg_appointment.users
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,3 @@
+ALTER TABLE stg_appointment.users
+    ADD COLUMN password_hash TEXT DEFAULT NULL,        -- bcrypt, NULL for accounts that sign in by username only
+    ADD COLUMN disabled_at TIMESTAMPTZ DEFAULT NULL;
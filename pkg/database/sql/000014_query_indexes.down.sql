@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invitations_invitee_appointment;
+DROP INDEX IF EXISTS idx_appointments_host_start_time;
+DROP INDEX IF EXISTS idx_appointments_active_start_time;
@@ -0,0 +1,5 @@
+-- appointmentRepository.GetAppointmentsByUserId: appointments in a time range,
+-- either hosted by the user or with an invitation of the user
+CREATE INDEX idx_appointments_active_start_time ON appointments (start_time) WHERE cancelled_at IS NULL;
+CREATE INDEX idx_appointments_host_start_time ON appointments (host_id, start_time);
+CREATE INDEX idx_invitations_invitee_appointment ON invitations (invitee_id, appointment_id);
@@ -0,0 +1,2 @@
+ALTER TABLE invitations DROP COLUMN IF EXISTS version;
+ALTER TABLE appointments DROP COLUMN IF EXISTS version;
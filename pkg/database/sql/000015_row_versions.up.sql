@@ -0,0 +1,4 @@
+-- optimistic concurrency: every update bumps the version, clients send the
+-- version they read as If-Match and the update is refused if it moved on
+ALTER TABLE appointments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE invitations ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
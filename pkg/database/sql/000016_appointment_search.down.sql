@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_appointments_tags;
+DROP INDEX IF EXISTS idx_appointments_search_vector;
+ALTER TABLE appointments DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE appointments DROP COLUMN IF EXISTS tags;
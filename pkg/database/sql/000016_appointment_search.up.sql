@@ -0,0 +1,10 @@
+-- appointmentRepository.SearchAppointments: full-text search on the title
+-- and the description without its markup, title matches ranking higher
+ALTER TABLE appointments ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE appointments ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', regexp_replace(coalesce(description, ''), '<[^>]*>', ' ', 'g')), 'B')
+) STORED;
+
+CREATE INDEX idx_appointments_search_vector ON appointments USING gin (search_vector);
+CREATE INDEX idx_appointments_tags ON appointments USING gin (tags);
@@ -0,0 +1,17 @@
+-- users observe only the holidays of the calendars they are assigned to,
+-- e.g. the calendar of their country or office
+CREATE TABLE holiday_calendar_users (
+    holiday_calendar_id INT NOT NULL REFERENCES holiday_calendars(holiday_calendar_id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (holiday_calendar_id, user_id)
+);
+
+CREATE INDEX idx_holiday_calendar_users_user ON holiday_calendar_users (user_id);
+
+-- every calendar used to apply to everyone, existing users keep that
+INSERT INTO holiday_calendar_users (holiday_calendar_id, user_id)
+SELECT c.holiday_calendar_id, u.user_id
+FROM holiday_calendars c
+CROSS JOIN users u
+WHERE u.deleted_at IS NULL;
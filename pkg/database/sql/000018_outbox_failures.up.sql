@@ -0,0 +1,14 @@
+-- failed attempts of a handler at an event. The handler retries the event,
+-- with backoff and ahead of any later event of its appointment, until it is
+-- dead-lettered.
+CREATE TABLE outbox_failures (
+    outbox_event_id BIGINT NOT NULL REFERENCES outbox_events(outbox_event_id) ON DELETE CASCADE,
+    handler VARCHAR(50) NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL,
+    retry_at TIMESTAMPTZ NOT NULL,
+    dead_lettered_at TIMESTAMPTZ,  -- set once the handler gave up, the event is then skipped
+    PRIMARY KEY (outbox_event_id, handler)
+);
+
+CREATE INDEX idx_outbox_failures_handler ON outbox_failures (handler, outbox_event_id);
@@ -0,0 +1 @@
+-- btree_gist stays in public, the earlier down migrations find it there.
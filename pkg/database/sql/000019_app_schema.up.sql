@@ -0,0 +1,19 @@
+-- every schema but stg_appointment creates its tables in place, see
+-- database.Migrator. btree_gist is moved to public so that every schema
+-- of the database can use it; stg_appointment keeps it where its first
+-- migrations put it.
+DO $$
+BEGIN
+    IF current_schema() = 'stg_appointment' THEN
+        RETURN;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1
+        FROM pg_extension e
+        JOIN pg_namespace n ON n.oid = e.extnamespace
+        WHERE e.extname = 'btree_gist' AND n.nspname <> 'public'
+    ) THEN
+        ALTER EXTENSION btree_gist SET SCHEMA public;
+    END IF;
+END $$;
@@ -0,0 +1,2 @@
+-- outboxRepository.ClaimEvents: earlier events of the same appointment
+CREATE INDEX idx_outbox_events_appointment ON outbox_events (appointment_id, outbox_event_id);
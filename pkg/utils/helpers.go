@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 	"time"
 
@@ -31,6 +33,18 @@ func GetFriendlyErrorMessage(e validator.FieldError, s interface{}) (string, str
 		return jsonTag, jsonTag + " must have at least " + e.Param() + " characters"
 	case "max":
 		return jsonTag, jsonTag + " must have no more than " + e.Param() + " characters"
+	case "gtfield":
+		otherTag := strings.ToLower(e.Param())
+		if other, ok := t.FieldByName(e.Param()); ok && other.Tag.Get("json") != "" {
+			otherTag = other.Tag.Get("json")
+		}
+		return jsonTag, jsonTag + " must be after " + otherTag
+	case "oneof":
+		return jsonTag, jsonTag + " must be one of: " + e.Param()
+	case "datetime":
+		return jsonTag, jsonTag + " must match format " + e.Param()
+	case "slug":
+		return jsonTag, jsonTag + " must contain only lowercase letters, digits and dashes"
 	case "ISOdate":
 		return jsonTag, jsonTag + "must in ISO 8601 date format"
 	default:
@@ -38,6 +52,12 @@ func GetFriendlyErrorMessage(e validator.FieldError, s interface{}) (string, str
 	}
 }
 
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+func SlugValidator(fl validator.FieldLevel) bool {
+	return slugPattern.MatchString(fl.Field().String())
+}
+
 func ISODateValidator(fl validator.FieldLevel) bool {
 	field := fl.Field()
 
@@ -52,3 +72,63 @@ func ISODateValidator(fl validator.FieldLevel) bool {
 
 	return !t.IsZero()
 }
+
+// ParseTimeRange reads an ISO 8601 start/end pair from query params. Missing
+// values default to now and now + defaultSpan.
+func ParseTimeRange(start, end string, defaultSpan time.Duration) (time.Time, time.Time, error) {
+	startTime := time.Now().UTC()
+	if start != "" {
+		parsed, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("start must in ISO 8601 format")
+		}
+		startTime = parsed
+	}
+
+	endTime := startTime.Add(defaultSpan)
+	if end != "" {
+		parsed, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("end must in ISO 8601 format")
+		}
+		endTime = parsed
+	}
+
+	if !endTime.After(startTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end must be after start")
+	}
+
+	return startTime, endTime, nil
+}
+
+// NormalizeTags lowercases and trims tags, dropping empty and duplicate ones
+// while keeping the original order.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := []string{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// UniqueInts drops duplicate values while keeping the original order.
+func UniqueInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	var result []int
+
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICSEvent is the subset of a VEVENT the application reads and writes.
+type ICSEvent struct {
+	Uid         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+// ParseICSEvents reads the VEVENTs of an iCalendar (RFC 5545) stream. Events
+// without DTEND last one day when all-day and zero time otherwise.
+func ParseICSEvents(r io.Reader) ([]ICSEvent, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ICSEvent
+	var current *ICSEvent
+	var hasEnd bool
+
+	for _, line := range lines {
+		name, params, value := splitICSLine(line)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &ICSEvent{}
+			hasEnd = false
+		case name == "END" && value == "VEVENT":
+			if current == nil {
+				return nil, fmt.Errorf("ics: END:VEVENT without BEGIN")
+			}
+			if current.Start.IsZero() {
+				return nil, fmt.Errorf("ics: event %q has no DTSTART", current.Uid)
+			}
+			if !hasEnd {
+				current.End = current.Start
+				if current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+			if current.Uid == "" {
+				current.Uid = fmt.Sprintf("%s-%s", current.Start.Format("20060102"), current.Summary)
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.Uid = value
+		case name == "SUMMARY":
+			current.Summary = unescapeICSText(value)
+		case name == "DTSTART":
+			current.Start, current.AllDay, err = parseICSTime(params, value)
+			if err != nil {
+				return nil, err
+			}
+		case name == "DTEND":
+			current.End, _, err = parseICSTime(params, value)
+			if err != nil {
+				return nil, err
+			}
+			hasEnd = true
+		}
+	}
+
+	return events, nil
+}
+
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ics: read: %w", err)
+	}
+
+	return lines, nil
+}
+
+func splitICSLine(line string) (string, map[string]string, string) {
+	head, value, _ := strings.Cut(line, ":")
+
+	parts := strings.Split(head, ";")
+	params := make(map[string]string)
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = v
+	}
+
+	return strings.ToUpper(parts[0]), params, value
+}
+
+func parseICSTime(params map[string]string, value string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("ics: invalid date %q", value)
+		}
+		return t, true, nil
+	}
+
+	loc := time.UTC
+	if tzid, ok := params["TZID"]; ok {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("ics: unknown TZID %q", tzid)
+		}
+		loc = l
+	}
+
+	layout := "20060102T150405"
+	if strings.HasSuffix(value, "Z") {
+		layout = "20060102T150405Z"
+	}
+
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("ics: invalid date-time %q", value)
+	}
+
+	return t, false, nil
+}
+
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// BuildICS renders the events as an iCalendar document with UTC times.
+func BuildICS(events []ICSEvent) string {
+	var b strings.Builder
+
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//be-appointment-system//EN")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+event.Uid)
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		if event.AllDay {
+			writeICSLine(&b, "DTSTART;VALUE=DATE:"+event.Start.Format("20060102"))
+			writeICSLine(&b, "DTEND;VALUE=DATE:"+event.End.Format("20060102"))
+		} else {
+			writeICSLine(&b, "DTSTART:"+event.Start.UTC().Format("20060102T150405Z"))
+			writeICSLine(&b, "DTEND:"+event.End.UTC().Format("20060102T150405Z"))
+		}
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(event.Summary))
+		if event.Description != "" {
+			writeICSLine(&b, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		if event.Location != "" {
+			writeICSLine(&b, "LOCATION:"+escapeICSText(event.Location))
+		}
+		if event.URL != "" {
+			writeICSLine(&b, "URL:"+event.URL)
+		}
+		writeICSLine(&b, "END:VEVENT")
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+
+	return b.String()
+}
+
+// writeICSLine folds content lines longer than 75 octets as RFC 5545 requires.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// never split a multi-byte character
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// continuation lines start with the folding space
+		limit = 74
+	}
+	b.WriteString(line + "\r\n")
+}
+
+func escapeICSText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestParseICSEvents(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ics  string
+		want []ICSEvent
+	}{
+		{
+			name: "all-day event without DTEND lasts one day",
+			ics: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:new-year\r\nSUMMARY:New Year\r\n" +
+				"DTSTART;VALUE=DATE:20250101\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []ICSEvent{{
+				Uid: "new-year", Summary: "New Year", AllDay: true,
+				Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "utc and zoned times",
+			ics: "BEGIN:VEVENT\nUID:a\nSUMMARY:Offsite\nDTSTART:20250310T090000Z\nDTEND:20250310T170000Z\nEND:VEVENT\n" +
+				"BEGIN:VEVENT\nUID:b\nSUMMARY:Standup\nDTSTART;TZID=Asia/Jakarta:20250311T090000\n" +
+				"DTEND;TZID=Asia/Jakarta:20250311T091500\nEND:VEVENT\n",
+			want: []ICSEvent{
+				{
+					Uid: "a", Summary: "Offsite",
+					Start: time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 10, 17, 0, 0, 0, time.UTC),
+				},
+				{
+					Uid: "b", Summary: "Standup",
+					Start: time.Date(2025, 3, 11, 9, 0, 0, 0, jakarta), End: time.Date(2025, 3, 11, 9, 15, 0, 0, jakarta),
+				},
+			},
+		},
+		{
+			name: "folded lines and escaped text",
+			ics: "BEGIN:VEVENT\r\nUID:c\r\nSUMMARY:Company day\\, all\r\n  offices\\; no meetings\r\n" +
+				"DTSTART:20250501\r\nEND:VEVENT\r\n",
+			want: []ICSEvent{{
+				Uid: "c", Summary: "Company day, all offices; no meetings", AllDay: true,
+				Start: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "missing UID is derived from date and summary",
+			ics:  "BEGIN:VEVENT\nSUMMARY:Labour Day\nDTSTART;VALUE=DATE:20250501\nDTEND;VALUE=DATE:20250502\nEND:VEVENT\n",
+			want: []ICSEvent{{
+				Uid: "20250501-Labour Day", Summary: "Labour Day", AllDay: true,
+				Start: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "properties outside events are ignored",
+			ics:  "BEGIN:VCALENDAR\nSUMMARY:not an event\nEND:VCALENDAR\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseICSEvents(strings.NewReader(tt.ics))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Uid != w.Uid || g.Summary != w.Summary || g.AllDay != w.AllDay || !g.Start.Equal(w.Start) || !g.End.Equal(w.End) {
+					t.Errorf("event %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseICSEventsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ics  string
+	}{
+		{"end without begin", "END:VEVENT\n"},
+		{"no DTSTART", "BEGIN:VEVENT\nUID:x\nEND:VEVENT\n"},
+		{"invalid date", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:2025-01-01\nEND:VEVENT\n"},
+		{"invalid date-time", "BEGIN:VEVENT\nDTSTART:20250101T25\nEND:VEVENT\n"},
+		{"unknown TZID", "BEGIN:VEVENT\nDTSTART;TZID=Mars/Olympus:20250101T090000\nEND:VEVENT\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if events, err := ParseICSEvents(strings.NewReader(tt.ics)); err == nil {
+				t.Fatalf("parsed %+v, want an error", events)
+			}
+		})
+	}
+}
+
+func TestBuildICS(t *testing.T) {
+	start := time.Date(2025, 3, 10, 16, 0, 0, 0, time.FixedZone("WIB", 7*3600))
+	events := []ICSEvent{
+		{
+			Uid: "appointment-1@be-appointment-system", Summary: "Review; budget, Q2", Description: "line one\nline two",
+			Location: "Room 4", URL: "https://meet.example.com/abc", Start: start, End: start.Add(30 * time.Minute),
+		},
+		{
+			Uid: "holiday-1", Summary: "Holiday", AllDay: true,
+			Start: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	ics := BuildICS(events)
+
+	for _, line := range []string{
+		"BEGIN:VCALENDAR", "VERSION:2.0", "END:VCALENDAR",
+		"DTSTART:20250310T090000Z", "DTEND:20250310T093000Z",
+		`SUMMARY:Review\; budget\, Q2`, `DESCRIPTION:line one\nline two`,
+		"LOCATION:Room 4", "URL:https://meet.example.com/abc",
+		"DTSTART;VALUE=DATE:20250501", "DTEND;VALUE=DATE:20250502",
+	} {
+		if !strings.Contains(ics, line+"\r\n") {
+			t.Errorf("missing line %q in\n%s", line, ics)
+		}
+	}
+
+	parsed, err := ParseICSEvents(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 2 {
+		t.Fatalf("parsed %d events back, want 2", len(parsed))
+	}
+	if parsed[0].Summary != events[0].Summary || !parsed[0].Start.Equal(events[0].Start) || !parsed[0].End.Equal(events[0].End) {
+		t.Errorf("round trip gave %+v", parsed[0])
+	}
+	if !parsed[1].AllDay || !parsed[1].Start.Equal(events[1].Start) {
+		t.Errorf("all-day round trip gave %+v", parsed[1])
+	}
+}
+
+func TestBuildICSFoldsLongLines(t *testing.T) {
+	summary := strings.Repeat("Jadwal rapat ", 5) + strings.Repeat("日本語", 20)
+	ics := BuildICS([]ICSEvent{{Uid: "long", Summary: summary, Start: time.Now(), End: time.Now()}})
+
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("fold split a character: %q", line)
+		}
+	}
+
+	parsed, err := ParseICSEvents(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 1 || parsed[0].Summary != summary {
+		t.Fatalf("unfolded %+v, want summary %q", parsed, summary)
+	}
+}
@@ -3,50 +3,60 @@ package utils
 import (
 	"errors"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/ghofaralhasyim/be-appointment-system/internal/models"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var secretKey = os.Getenv("JWT_SECRET_KEY")
-var refreshSecretKey = os.Getenv("JWT_REFRESH_KEY")
-
-func GenerateSessionToken(sessionId string) (*models.JwtToken, error) {
-	var jwtToken models.JwtToken
+// TokenIssuer signs and verifies the access and refresh tokens of sessions.
+type TokenIssuer struct {
+	secretKey  []byte
+	refreshKey []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
 
-	hoursCount, err := strconv.Atoi(os.Getenv("JWT_EXPIRE_HOURS"))
-	if err != nil {
-		return nil, err
+func NewTokenIssuer(secretKey, refreshKey string, accessTTL, refreshTTL time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		secretKey:  []byte(secretKey),
+		refreshKey: []byte(refreshKey),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
 	}
+}
+
+func (t *TokenIssuer) AccessTTL() time.Duration {
+	return t.accessTTL
+}
+
+func (t *TokenIssuer) RefreshTTL() time.Duration {
+	return t.refreshTTL
+}
+
+func (t *TokenIssuer) GenerateSessionToken(sessionId string) (*models.JwtToken, error) {
+	var jwtToken models.JwtToken
 
 	claims := jwt.MapClaims{}
 	claims["session_id"] = sessionId
-	claims["exp"] = time.Now().Add(time.Hour * time.Duration(hoursCount)).Unix()
+	claims["exp"] = time.Now().Add(t.accessTTL).Unix()
 
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	accessTokenString, err := accessToken.SignedString([]byte(secretKey))
+	accessTokenString, err := accessToken.SignedString(t.secretKey)
 	if err != nil {
 		return nil, err
 	}
 
 	jwtToken.AccessToken = accessTokenString
 
-	hoursCount, err = strconv.Atoi(os.Getenv("JWT_REFRESH_EXPIRE_HOURS"))
-	if err != nil {
-		return nil, err
-	}
-
 	refreshClaims := jwt.MapClaims{
 		"session_id": sessionId,
-		"exp":        time.Now().Add(time.Hour * 24 * time.Duration(hoursCount)).Unix(),
+		"exp":        time.Now().Add(t.refreshTTL).Unix(),
 	}
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(refreshSecretKey))
+	refreshTokenString, err := refreshToken.SignedString(t.refreshKey)
 	if err != nil {
 		return nil, err
 	}
@@ -56,15 +66,18 @@ func GenerateSessionToken(sessionId string) (*models.JwtToken, error) {
 	return &jwtToken, nil
 }
 
-func VerifyToken(tokenString string, isRefreshToken bool) (*jwt.Token, error) {
+func (t *TokenIssuer) VerifyToken(tokenString string, isRefreshToken bool) (*jwt.Token, error) {
 
-	key := secretKey
+	key := t.secretKey
 	if isRefreshToken {
-		key = refreshSecretKey
+		key = t.refreshKey
 	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(key), nil
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+var allowedTags = map[string]bool{
+	"p": true, "br": true, "b": true, "strong": true, "i": true, "em": true, "u": true,
+	"ul": true, "ol": true, "li": true, "a": true, "blockquote": true, "code": true, "pre": true,
+}
+
+// tags whose content is dropped together with the tag
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "template": true,
+}
+
+// SanitizeHTML keeps a small allowlist of formatting tags and drops everything
+// else. Attributes are removed except a safe href on links.
+func SanitizeHTML(input string) string {
+	var out strings.Builder
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(input))
+	skipDepth := 0
+
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			return out.String()
+		}
+
+		token := tokenizer.Token()
+
+		switch tt {
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if droppedTags[token.Data] {
+				if tt == xhtml.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 || !allowedTags[token.Data] {
+				continue
+			}
+
+			out.WriteString("<" + token.Data)
+			if token.Data == "a" {
+				for _, attr := range token.Attr {
+					if attr.Key == "href" && isSafeURL(attr.Val) {
+						out.WriteString(` href="` + html.EscapeString(attr.Val) + `" rel="noopener noreferrer"`)
+					}
+				}
+			}
+			if token.Data == "br" {
+				out.WriteString(" /")
+			}
+			out.WriteString(">")
+		case xhtml.EndTagToken:
+			if droppedTags[token.Data] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 || !allowedTags[token.Data] || token.Data == "br" {
+				continue
+			}
+			out.WriteString("</" + token.Data + ">")
+		case xhtml.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			out.WriteString(html.EscapeString(token.Data))
+		}
+	}
+}
+
+// StripHTML turns (sanitized) rich text into plain text, keeping line breaks
+// of block elements.
+func StripHTML(input string) string {
+	var out strings.Builder
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(input))
+
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			return strings.TrimSpace(out.String())
+		}
+
+		token := tokenizer.Token()
+		switch tt {
+		case xhtml.TextToken:
+			out.WriteString(token.Data)
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if token.Data == "br" || token.Data == "li" {
+				out.WriteString("\n")
+			}
+		case xhtml.EndTagToken:
+			if token.Data == "p" || token.Data == "ul" || token.Data == "ol" || token.Data == "blockquote" {
+				out.WriteString("\n")
+			}
+		}
+	}
+}
+
+func isSafeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
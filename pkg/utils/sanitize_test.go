@@ -0,0 +1,48 @@
+package utils
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "Agenda & notes", "Agenda &amp; notes"},
+		{"allowed formatting", "<p>Bring <b>slides</b><br>and <em>notes</em></p>", "<p>Bring <b>slides</b><br />and <em>notes</em></p>"},
+		{"attributes removed", `<p class="x" onclick="alert(1)">hi</p>`, "<p>hi</p>"},
+		{"safe link", `<a href="https://example.com/doc?a=1&b=2" target="_blank">doc</a>`,
+			`<a href="https://example.com/doc?a=1&amp;b=2" rel="noopener noreferrer">doc</a>`},
+		{"mailto link", `<a href="mailto:host@example.com">mail</a>`, `<a href="mailto:host@example.com" rel="noopener noreferrer">mail</a>`},
+		{"javascript link", `<a href="javascript:alert(1)">click</a>`, "<a>click</a>"},
+		{"script dropped with its content", "before<script>alert(1)</script>after", "beforeafter"},
+		{"nested dropped tags", "<style><script>x</script>y</style>kept", "kept"},
+		{"unknown tags unwrapped", "<div><span>text</span></div>", "text"},
+		{"iframe dropped", `<iframe src="https://evil.example"></iframe>ok`, "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.input); got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"<p>First</p><p>Second</p>", "First\nSecond"},
+		{"one<br />two", "one\ntwo"},
+		{"<ul><li>a</li><li>b</li></ul>", "a\nb"},
+		{"<b>bold</b> text", "bold text"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := StripHTML(tt.input); got != tt.want {
+			t.Errorf("StripHTML(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
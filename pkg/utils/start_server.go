@@ -2,27 +2,26 @@ package utils
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
-func StartServer(e *echo.Echo) {
-	connUrl := fmt.Sprintf("%s:%s", os.Getenv("SERVER_HOST"), os.Getenv("SERVER_PORT"))
-
+func StartServer(e *echo.Echo, connUrl string) {
 	if err := e.Start(connUrl); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("shutting down the server: %s", err)
 	}
 }
 
-func StartServerWithGracefulShutdown(e *echo.Echo) {
-	connUrl := fmt.Sprintf("%s:%s", os.Getenv("SERVER_HOST"), os.Getenv("SERVER_PORT"))
-
+// StartServerWithGracefulShutdown serves until SIGINT or SIGTERM. It then calls
+// drain, so readiness probes start failing, and keeps serving for drainDelay
+// while load balancers take the instance out before shutting the server down.
+func StartServerWithGracefulShutdown(e *echo.Echo, connUrl string, drain func(), drainDelay time.Duration) {
 	go func() {
 		if err := e.Start(connUrl); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("shutting down the server: %s", err)
@@ -30,8 +29,13 @@ func StartServerWithGracefulShutdown(e *echo.Echo) {
 	}()
 
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
+
+	log.Printf("Draining server for %s...", drainDelay)
+	drain()
+	time.Sleep(drainDelay)
+
 	log.Println("Shutting down server...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// NewWebhookSecret returns a signing secret of 32 random bytes.
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generate webhook secret: %w", err)
+	}
+
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SignWebhook returns the X-Webhook-Signature value of a webhook body. The
+// timestamp is signed too, so receivers can reject replayed requests.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks a signature produced by SignWebhook in constant time.
+func VerifyWebhook(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestSignWebhook(t *testing.T) {
+	// computed independently: HMAC-SHA256 of `1700000000.{"id":"evt_1"}`
+	want := "sha256=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+	if got := SignWebhook("whsec_test", 1700000000, []byte(`{"id":"evt_1"}`)); got != want {
+		t.Fatalf("signature %s, want %s", got, want)
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	const secret = "whsec_test"
+	const timestamp = int64(1700000000)
+	body := []byte(`{"id":"evt_1"}`)
+	signature := SignWebhook(secret, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		signature string
+		valid     bool
+	}{
+		{"valid", secret, timestamp, body, signature, true},
+		{"tampered body", secret, timestamp, []byte(`{"id":"evt_2"}`), signature, false},
+		{"replayed with another timestamp", secret, timestamp + 1, body, signature, false},
+		{"other secret", "whsec_other", timestamp, body, signature, false},
+		{"missing prefix", secret, timestamp, body, strings.TrimPrefix(signature, "sha256="), false},
+		{"upper case hex", secret, timestamp, body, strings.ToUpper(signature), false},
+		{"empty signature", secret, timestamp, body, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhook(tt.secret, tt.timestamp, tt.body, tt.signature); got != tt.valid {
+				t.Errorf("VerifyWebhook = %v, want %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestNewWebhookSecret(t *testing.T) {
+	a, err := NewWebhookSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewWebhookSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(a, "whsec_") {
+		t.Errorf("secret %q has no whsec_ prefix", a)
+	}
+	if raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(a, "whsec_")); err != nil || len(raw) != 32 {
+		t.Errorf("secret %q does not hold 32 random bytes: %v", a, err)
+	}
+	if a == b {
+		t.Error("two secrets are equal")
+	}
+}